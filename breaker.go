@@ -0,0 +1,266 @@
+package qvo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+//Circuit breaker states.
+const (
+	CircuitClosed   CircuitState = iota //Requests flow normally.
+	CircuitOpen                         //Requests fail fast.
+	CircuitHalfOpen                     //A few probe requests are let through to check if the API recovered.
+)
+
+//String returns the state's name.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+//ErrCircuitOpen is matched with errors.Is by the errors returned while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("qvo: circuit breaker is open")
+
+//CircuitOpenError is returned without making a request while the circuit breaker is open, or while its half-open probes are in flight.
+type CircuitOpenError struct {
+	State   CircuitState
+	RetryAt time.Time //When the breaker lets probe requests through.
+}
+
+//Error describes the breaker state.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("qvo: circuit breaker is %s until %s", e.State, e.RetryAt.Format(time.RFC3339))
+}
+
+//Is makes errors.Is(err, ErrCircuitOpen) work.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+//CircuitBreakerSettings configures when a CircuitBreaker trips and how it recovers.
+//Network errors, timeouts and 5xx responses count as failures; other API errors don't, and calls the caller gave up on aren't counted at all.
+type CircuitBreakerSettings struct {
+	ConsecutiveFailures int           //Trips after this many failures in a row. 0 disables it.
+	ErrorRate           float64       //Trips when the failure ratio in the current window reaches this, in (0, 1]. 0 disables it.
+	MinRequests         int           //Requests needed in the window before the error rate is considered.
+	Window              time.Duration //Length of the window for the error rate. Counts are reset when it ends.
+	OpenTimeout         time.Duration //How long the breaker stays open before going half-open.
+	HalfOpenRequests    int           //Probe requests let through while half-open. The breaker closes once they all succeed, and opens again on any failure.
+	OnStateChange       func(from, to CircuitState)
+}
+
+//DefaultCircuitBreakerSettings trips after 5 consecutive failures, or a 50% error rate over at least 20 requests in a minute, and probes again after 30 seconds.
+func DefaultCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		ConsecutiveFailures: 5,
+		ErrorRate:           0.5,
+		MinRequests:         20,
+		Window:              time.Minute,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenRequests:    1,
+	}
+}
+
+//CircuitBreaker fails requests fast while the API seems degraded, instead of having every caller wait for its timeout. It's safe for concurrent use.
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu             sync.Mutex
+	state          CircuitState
+	openedAt       time.Time
+	windowStart    time.Time
+	requests       int
+	failures       int
+	consecutive    int
+	probes         int
+	probeSuccesses int
+	generation     int //Bumped on every state change, so results of requests allowed in an older state are dropped.
+}
+
+//NewCircuitBreaker returns a closed breaker with the given settings.
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	if settings.HalfOpenRequests < 1 {
+		settings.HalfOpenRequests = 1
+	}
+	return &CircuitBreaker{
+		settings:    settings,
+		windowStart: time.Now(),
+	}
+}
+
+//WithCircuitBreaker makes every attempt of a request go through the given breaker.
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(c *Client) {
+		c.circuitBreaker = cb
+	}
+}
+
+//State returns the breaker's current state.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	from := cb.state
+	cb.checkTimeout(time.Now())
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	return to
+}
+
+//outcome is how a request's result counts for the breaker.
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota //The API answered.
+	outcomeFailure                //The API seems degraded.
+	outcomeIgnored                //The caller gave up before the API answered, so it tells nothing about it.
+)
+
+//allow tells if a request may be made. If so, done must be called with the request's outcome.
+func (cb *CircuitBreaker) allow() (done func(outcome), err error) {
+	cb.mu.Lock()
+	from := cb.state
+	now := time.Now()
+	cb.checkTimeout(now)
+
+	switch cb.state {
+	case CircuitOpen:
+		err = &CircuitOpenError{State: CircuitOpen, RetryAt: cb.openedAt.Add(cb.settings.OpenTimeout)}
+	case CircuitHalfOpen:
+		if cb.probes >= cb.settings.HalfOpenRequests {
+			err = &CircuitOpenError{State: CircuitHalfOpen, RetryAt: now.Add(cb.settings.OpenTimeout)}
+		} else {
+			cb.probes++
+		}
+	}
+	to := cb.state
+	generation := cb.generation
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return func(o outcome) {
+		cb.record(generation, o)
+	}, nil
+}
+
+//record updates the breaker with the outcome of a request allowed in the given generation.
+//Outcomes from an older generation are dropped, and ignored probes free their slot for another one.
+func (cb *CircuitBreaker) record(generation int, o outcome) {
+	cb.mu.Lock()
+	from := cb.state
+	now := time.Now()
+
+	switch {
+	case generation != cb.generation:
+	case o == outcomeIgnored:
+		if cb.state == CircuitHalfOpen {
+			cb.probes--
+		}
+	case cb.state == CircuitHalfOpen:
+		if o == outcomeFailure {
+			cb.open(now)
+		} else {
+			cb.probeSuccesses++
+			if cb.probeSuccesses >= cb.settings.HalfOpenRequests {
+				cb.close(now)
+			}
+		}
+	case cb.state == CircuitClosed:
+		if cb.settings.Window > 0 && now.Sub(cb.windowStart) > cb.settings.Window {
+			cb.windowStart, cb.requests, cb.failures = now, 0, 0
+		}
+		cb.requests++
+		if o == outcomeFailure {
+			cb.failures++
+			cb.consecutive++
+		} else {
+			cb.consecutive = 0
+		}
+		if cb.shouldTrip() {
+			cb.open(now)
+		}
+	}
+
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
+
+//shouldTrip checks the counts against the settings.
+func (cb *CircuitBreaker) shouldTrip() bool {
+	if cb.settings.ConsecutiveFailures > 0 && cb.consecutive >= cb.settings.ConsecutiveFailures {
+		return true
+	}
+	if cb.settings.ErrorRate > 0 && cb.requests >= cb.settings.MinRequests && cb.requests > 0 {
+		return float64(cb.failures)/float64(cb.requests) >= cb.settings.ErrorRate
+	}
+	return false
+}
+
+//checkTimeout moves an open breaker to half-open once its timeout is over.
+func (cb *CircuitBreaker) checkTimeout(now time.Time) {
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.settings.OpenTimeout {
+		cb.state = CircuitHalfOpen
+		cb.probes, cb.probeSuccesses = 0, 0
+		cb.generation++
+	}
+}
+
+func (cb *CircuitBreaker) open(now time.Time) {
+	cb.state = CircuitOpen
+	cb.openedAt = now
+	cb.generation++
+}
+
+func (cb *CircuitBreaker) close(now time.Time) {
+	cb.state = CircuitClosed
+	cb.generation++
+	cb.windowStart, cb.requests, cb.failures, cb.consecutive = now, 0, 0, 0
+}
+
+//notify calls OnStateChange, if set, for every state the breaker went through. It's called without holding the lock.
+func (cb *CircuitBreaker) notify(from, to CircuitState) {
+	if from == to || cb.settings.OnStateChange == nil {
+		return
+	}
+	//An open breaker found half-open may have closed or opened again in the same step.
+	if from == CircuitOpen && to != CircuitHalfOpen {
+		cb.settings.OnStateChange(from, CircuitHalfOpen)
+		from = CircuitHalfOpen
+	}
+	cb.settings.OnStateChange(from, to)
+}
+
+//breakerOutcome tells how an attempt counts for the breaker. Network errors, timeouts and 5xx responses mean the API is degraded.
+//Failed calls whose ctx is done are ignored, as the caller gave up before the API answered.
+func breakerOutcome(ctx context.Context, err error) outcome {
+	if err == nil {
+		return outcomeSuccess
+	}
+	if ctx.Err() != nil {
+		return outcomeIgnored
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+		return outcomeSuccess
+	}
+	return outcomeFailure
+}
@@ -0,0 +1,158 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("Given a local server and a client with a circuit breaker", t, func() {
+		var hits int32
+		var status int32 = http.StatusInternalServerError
+		var hang int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			if atomic.LoadInt32(&hang) == 1 {
+				<-r.Context().Done()
+				return
+			}
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			w.Write([]byte(`{"id": "cus_1"}`))
+		}))
+		defer srv.Close()
+
+		var mu sync.Mutex
+		var changes []string
+		cb := NewCircuitBreaker(CircuitBreakerSettings{
+			ConsecutiveFailures: 3,
+			OpenTimeout:         50 * time.Millisecond,
+			OnStateChange: func(from, to CircuitState) {
+				mu.Lock()
+				defer mu.Unlock()
+				changes = append(changes, from.String()+"->"+to.String())
+			},
+		})
+		c := NewClient("token", true, WithBaseURL(srv.URL), WithCircuitBreaker(cb))
+
+		Convey("It should open after consecutive failures and fail fast", func() {
+			for i := 0; i < 3; i++ {
+				_, err := GetCustomer(c, "cus_1")
+				So(errors.Is(err, ErrServer), ShouldBeTrue)
+			}
+			So(cb.State(), ShouldEqual, CircuitOpen)
+
+			_, err := GetCustomer(c, "cus_1")
+			So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+			var openErr *CircuitOpenError
+			So(errors.As(err, &openErr), ShouldBeTrue)
+			So(openErr.State, ShouldEqual, CircuitOpen)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 3)
+
+			Convey("A successful probe should close it", func() {
+				atomic.StoreInt32(&status, http.StatusOK)
+				time.Sleep(60 * time.Millisecond)
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+
+				_, err := GetCustomer(c, "cus_1")
+				So(err, ShouldBeNil)
+				So(cb.State(), ShouldEqual, CircuitClosed)
+				So(changes, ShouldResemble, []string{"closed->open", "open->half-open", "half-open->closed"})
+			})
+
+			Convey("A failed probe should open it again", func() {
+				time.Sleep(60 * time.Millisecond)
+				_, err := GetCustomer(c, "cus_1")
+				So(errors.Is(err, ErrServer), ShouldBeTrue)
+				So(cb.State(), ShouldEqual, CircuitOpen)
+				So(changes, ShouldResemble, []string{"closed->open", "open->half-open", "half-open->open"})
+			})
+
+			Convey("A probe the caller gave up on should neither close nor open it", func() {
+				atomic.StoreInt32(&hang, 1)
+				time.Sleep(60 * time.Millisecond)
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, err := GetCustomerWithContext(ctx, c, "cus_1")
+				So(err, ShouldNotBeNil)
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+
+				Convey("And should free its slot for another probe", func() {
+					atomic.StoreInt32(&hang, 0)
+					atomic.StoreInt32(&status, http.StatusOK)
+					_, err := GetCustomer(c, "cus_1")
+					So(err, ShouldBeNil)
+					So(cb.State(), ShouldEqual, CircuitClosed)
+				})
+			})
+		})
+
+		Convey("Client errors shouldn't count as failures", func() {
+			atomic.StoreInt32(&status, http.StatusNotFound)
+			for i := 0; i < 5; i++ {
+				GetCustomer(c, "cus_1")
+			}
+			So(cb.State(), ShouldEqual, CircuitClosed)
+		})
+
+		Convey("A success should reset the consecutive failures", func() {
+			GetCustomer(c, "cus_1")
+			GetCustomer(c, "cus_1")
+			atomic.StoreInt32(&status, http.StatusOK)
+			GetCustomer(c, "cus_1")
+			atomic.StoreInt32(&status, http.StatusInternalServerError)
+			GetCustomer(c, "cus_1")
+			So(cb.State(), ShouldEqual, CircuitClosed)
+		})
+	})
+
+	Convey("Given a breaker tripping on error rate", t, func() {
+		cb := NewCircuitBreaker(CircuitBreakerSettings{
+			ErrorRate:   0.5,
+			MinRequests: 4,
+			Window:      time.Minute,
+			OpenTimeout: time.Minute,
+		})
+
+		Convey("It shouldn't trip before the minimum requests", func() {
+			for _, o := range []outcome{outcomeFailure, outcomeSuccess, outcomeFailure} {
+				done, err := cb.allow()
+				So(err, ShouldBeNil)
+				done(o)
+			}
+			So(cb.State(), ShouldEqual, CircuitClosed)
+
+			Convey("And should trip once it's reached", func() {
+				done, err := cb.allow()
+				So(err, ShouldBeNil)
+				done(outcomeSuccess)
+				So(cb.State(), ShouldEqual, CircuitOpen)
+			})
+		})
+
+		Convey("Outcomes of requests allowed before a state change should be dropped", func() {
+			late, err := cb.allow()
+			So(err, ShouldBeNil)
+			for i := 0; i < 4; i++ {
+				done, err := cb.allow()
+				So(err, ShouldBeNil)
+				done(outcomeFailure)
+			}
+			So(cb.State(), ShouldEqual, CircuitOpen)
+
+			cb.mu.Lock()
+			cb.openedAt = cb.openedAt.Add(-time.Minute)
+			cb.mu.Unlock()
+			So(cb.State(), ShouldEqual, CircuitHalfOpen)
+			late(outcomeSuccess)
+			So(cb.State(), ShouldEqual, CircuitHalfOpen)
+		})
+	})
+}
@@ -1,14 +1,11 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 //Status constants
@@ -44,8 +41,46 @@ type Card struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
-//CreateCardInscription begins a card inscription request. If everything's ok, it'll return an inscription uid, the redirect url to send the customer to, and the expiration date for this transaction.
+//CardService handles customer cards. A Client exposes it as its Cards field, which may be replaced with a fake in tests.
+type CardService interface {
+	CreateInscription(ctx context.Context, customerID, returnURL string) (CardInscriptionResponse, error)
+	GetInscription(ctx context.Context, customerID, inscriptionUID string) (CardInscriptionState, error)
+	Get(ctx context.Context, customerID, cardID string) (Card, error)
+	Charge(ctx context.Context, customerID, cardID, description string, amount int64) (Transaction, error)
+	Delete(ctx context.Context, customerID, cardID string) error
+	List(ctx context.Context, customerID string) ([]Card, error)
+}
+
+//cardResource returns the cards endpoint of a customer.
+func cardResource(customerID string) resource[Card] {
+	return resource[Card]{path: fmt.Sprintf("customers/%s/cards", customerID), idKey: "card_id", parentKey: "customer_id", parentID: customerID}
+}
+
+//cardService implements CardService through a Client.
+type cardService struct {
+	c *Client
+}
+
+//cards returns the client's CardService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) cards() CardService {
+	if c.Cards != nil {
+		return c.Cards
+	}
+	return &cardService{c: c}
+}
+
+//CreateCardInscription calls CreateCardInscriptionWithContext with a background context.
 func CreateCardInscription(c *Client, customerID, returnURL string) (CardInscriptionResponse, error) {
+	return CreateCardInscriptionWithContext(context.Background(), c, customerID, returnURL)
+}
+
+//CreateCardInscriptionWithContext calls CreateInscription on the client's CardService.
+func CreateCardInscriptionWithContext(ctx context.Context, c *Client, customerID, returnURL string) (CardInscriptionResponse, error) {
+	return c.cards().CreateInscription(ctx, customerID, returnURL)
+}
+
+//CreateInscription begins a card inscription request. If everything's ok, it'll return an inscription uid, the redirect url to send the customer to, and the expiration date for this transaction.
+func (s *cardService) CreateInscription(ctx context.Context, customerID, returnURL string) (CardInscriptionResponse, error) {
 
 	endpoint := fmt.Sprintf("customers/%s/cards/inscriptions", customerID)
 
@@ -53,71 +88,57 @@ func CreateCardInscription(c *Client, customerID, returnURL string) (CardInscrip
 	form.Add("customer_id", customerID)
 	form.Add("return_url", returnURL)
 
-	body, err := c.request("POST", endpoint, form)
-	if err != nil {
-		return CardInscriptionResponse{}, err
-	}
-
-	var resp CardInscriptionResponse
-	err = json.Unmarshal(body, &resp)
-
-	if err != nil {
-		return CardInscriptionResponse{}, err
-	}
+	return call[CardInscriptionResponse](ctx, s.c, "POST", endpoint, form)
+}
 
-	return resp, nil
+//GetCardInscription calls GetCardInscriptionWithContext with a background context.
+func GetCardInscription(c *Client, customerID, inscriptionUID string) (CardInscriptionState, error) {
+	return GetCardInscriptionWithContext(context.Background(), c, customerID, inscriptionUID)
+}
 
+//GetCardInscriptionWithContext calls GetInscription on the client's CardService.
+func GetCardInscriptionWithContext(ctx context.Context, c *Client, customerID, inscriptionUID string) (CardInscriptionState, error) {
+	return c.cards().GetInscription(ctx, customerID, inscriptionUID)
 }
 
-//GetCardInscription returns the inscription's state and a card (if successful).
-func GetCardInscription(c *Client, customerID, inscriptionUID string) (CardInscriptionState, error) {
+//GetInscription returns the inscription's state and a card (if successful).
+func (s *cardService) GetInscription(ctx context.Context, customerID, inscriptionUID string) (CardInscriptionState, error) {
 	endpoint := fmt.Sprintf("customers/%s/cards/inscriptions/%s", customerID, inscriptionUID)
 
 	form := url.Values{}
 	form.Add("customer_id", customerID)
 	form.Add("inscription_uid", inscriptionUID)
 
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return CardInscriptionState{}, err
-	}
-
-	var cardInscriptionState CardInscriptionState
-	err = json.Unmarshal(body, &cardInscriptionState)
-
-	if err != nil {
-		return CardInscriptionState{}, err
-	}
-
-	return cardInscriptionState, nil
+	return call[CardInscriptionState](ctx, s.c, "GET", endpoint, form)
 }
 
-//GetCard returns a card given a customer id and a card id.
+//GetCard calls GetCardWithContext with a background context.
 func GetCard(c *Client, customerID, cardID string) (Card, error) {
-	endpoint := fmt.Sprintf("customers/%s/cards/%s", customerID, cardID)
-
-	form := url.Values{}
-	form.Add("customer_id", customerID)
-	form.Add("card_id", cardID)
-
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Card{}, err
-	}
+	return GetCardWithContext(context.Background(), c, customerID, cardID)
+}
 
-	var card Card
-	err = json.Unmarshal(body, &card)
+//GetCardWithContext calls Get on the client's CardService.
+func GetCardWithContext(ctx context.Context, c *Client, customerID, cardID string) (Card, error) {
+	return c.cards().Get(ctx, customerID, cardID)
+}
 
-	if err != nil {
-		return Card{}, err
-	}
+//Get returns a card given a customer id and a card id.
+func (s *cardService) Get(ctx context.Context, customerID, cardID string) (Card, error) {
+	return cardResource(customerID).get(ctx, s.c, cardID)
+}
 
-	return card, nil
+//ChargeCard calls ChargeCardWithContext with a background context.
+func ChargeCard(c *Client, customerID, cardID, description string, amount int64) (Transaction, error) {
+	return ChargeCardWithContext(context.Background(), c, customerID, cardID, description, amount)
+}
 
+//ChargeCardWithContext calls Charge on the client's CardService.
+func ChargeCardWithContext(ctx context.Context, c *Client, customerID, cardID, description string, amount int64) (Transaction, error) {
+	return c.cards().Charge(ctx, customerID, cardID, description, amount)
 }
 
-//ChargeCard creates a charge for given customer and card.
-func ChargeCard(c *Client, customerID, cardID, description string, amount int64) (Transaction, error) {
+//Charge creates a charge for given customer and card.
+func (s *cardService) Charge(ctx context.Context, customerID, cardID, description string, amount int64) (Transaction, error) {
 	endpoint := fmt.Sprintf("customers/%s/cards/%s/charge", customerID, cardID)
 
 	form := url.Values{}
@@ -126,61 +147,35 @@ func ChargeCard(c *Client, customerID, cardID, description string, amount int64)
 	form.Add("amount", strconv.FormatInt(amount, 10))
 	form.Add("description", description)
 
-	body, err := c.request("POST", endpoint, form)
-	if err != nil {
-		return Transaction{}, err
-	}
-
-	var transaction Transaction
-	err = json.Unmarshal(body, &transaction)
-
-	if err != nil {
-		return Transaction{}, err
-	}
-
-	return transaction, nil
+	return call[Transaction](ctx, s.c, "POST", endpoint, form)
 }
 
-//DeleteCard deletes a card for a given customer.
+//DeleteCard calls DeleteCardWithContext with a background context.
 func DeleteCard(c *Client, customerID, cardID string) error {
+	return DeleteCardWithContext(context.Background(), c, customerID, cardID)
+}
 
-	endpoint := fmt.Sprintf("customers/%s/cards/%s", customerID, cardID)
-
-	form := url.Values{}
-	form.Add("customer_id", customerID)
-	form.Add("card_id", cardID)
-
-	_, err := c.request("DELETE", endpoint, form)
-	if err != nil {
-		return err
-	}
-
-	return nil
+//DeleteCardWithContext calls Delete on the client's CardService.
+func DeleteCardWithContext(ctx context.Context, c *Client, customerID, cardID string) error {
+	return c.cards().Delete(ctx, customerID, cardID)
+}
 
+//Delete deletes a card for a given customer.
+func (s *cardService) Delete(ctx context.Context, customerID, cardID string) error {
+	return cardResource(customerID).delete(ctx, s.c, cardID, nil)
 }
 
-//ListCards retrieves cards for a given customer.
+//ListCards calls ListCardsWithContext with a background context.
 func ListCards(c *Client, customerID string) ([]Card, error) {
+	return ListCardsWithContext(context.Background(), c, customerID)
+}
 
-	var cards = make([]Card, 0)
-
-	form := url.Values{}
-	form.Add("customer_id", customerID)
-
-	body, err := c.request("GET", "customers", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return cards, err
-	}
-
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&cards)
-
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return cards, err
-	}
-
-	return cards, nil
+//ListCardsWithContext calls List on the client's CardService.
+func ListCardsWithContext(ctx context.Context, c *Client, customerID string) ([]Card, error) {
+	return c.cards().List(ctx, customerID)
+}
 
+//List retrieves cards for a given customer.
+func (s *cardService) List(ctx context.Context, customerID string) ([]Card, error) {
+	return cardResource(customerID).list(ctx, s.c, 0, 0, nil, "")
 }
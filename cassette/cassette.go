@@ -0,0 +1,399 @@
+//Package cassette records the requests a client sends, and the responses it gets, to cassette files, and replays them later so integration tests can run without network.
+//
+//A Recorder is an http.RoundTripper, so it plugs into a client with qvo.WithTransport:
+//
+//	rec, err := cassette.New("testdata/customers.json", cassette.WithMode(cassette.ModeAuto), cassette.WithSecrets(token))
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//	c := qvo.NewClient(token, true, qvo.WithTransport(rec))
+//
+//The authorization header is never saved, and the given secrets and qvo.DefaultRedactor, unless another redactor is given, mask the rest of what's saved.
+//When replaying, requests are matched against recorded ones by method, path and normalized form by default, and a request matching none fails with an *UnmatchedError.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/pkg/errors"
+)
+
+//Mode tells whether a Recorder replays or records.
+type Mode int
+
+//Recorder modes.
+const (
+	ModeReplay Mode = iota //Replay the cassette, failing requests it doesn't hold.
+	ModeRecord             //Send requests and record them, overwriting the cassette on Stop.
+	ModeAuto               //Replay the cassette if its file exists, record it otherwise.
+)
+
+//ErrUnmatched is matched with errors.Is by the errors returned for requests missing from the cassette.
+var ErrUnmatched = errors.New("cassette: no recorded interaction matches the request")
+
+//UnmatchedError is returned when replaying a request that matches no unused interaction.
+type UnmatchedError struct {
+	Cassette string
+	Request  Request
+}
+
+//Error describes the request.
+func (e *UnmatchedError) Error() string {
+	return fmt.Sprintf("cassette %s: no recorded interaction matches %s %s %q", e.Cassette, e.Request.Method, e.Request.Path, e.Request.Form)
+}
+
+//Is makes errors.Is(err, ErrUnmatched) work.
+func (e *UnmatchedError) Is(target error) bool {
+	return target == ErrUnmatched
+}
+
+//Request is a recorded request. Form holds the query and body values, normalized (sorted and without ignored fields) and redacted.
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Form   string      `json:"form"`
+	Header http.Header `json:"header"`
+}
+
+//Response is a recorded response, with its body redacted.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+//Interaction is a request and the response it got.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+//Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+//Matcher tells if a request matches a recorded one.
+type Matcher func(req, recorded Request) bool
+
+//MatchMethod matches requests with the same method.
+func MatchMethod(req, recorded Request) bool {
+	return req.Method == recorded.Method
+}
+
+//MatchPath matches requests with the same path.
+func MatchPath(req, recorded Request) bool {
+	return req.Path == recorded.Path
+}
+
+//MatchForm matches requests with the same normalized form.
+func MatchForm(req, recorded Request) bool {
+	return req.Form == recorded.Form
+}
+
+//DefaultMatchers match requests by method, path and normalized form.
+var DefaultMatchers = []Matcher{MatchMethod, MatchPath, MatchForm}
+
+//Redactor masks secrets and PII from what's saved. *qvo.Redactor implements it.
+type Redactor interface {
+	Redact(s string) string
+}
+
+//Option configures a Recorder.
+type Option func(*Recorder)
+
+//WithMode sets the recorder's mode. It's ModeReplay by default.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+//WithTransport sets the transport used to send requests when recording. It's http.DefaultTransport by default.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+//WithRedactor masks what's saved with the given redactor instead of qvo.DefaultRedactor, which masks names, emails, card data and the like.
+//Requests are redacted the same way before matching, so they still match when replayed.
+func WithRedactor(redactor Redactor) Option {
+	return func(r *Recorder) {
+		r.redactor = redactor
+	}
+}
+
+//WithoutRedaction saves what's sent and received as is, but for the authorization header and the given secrets.
+//Only use it for cassettes holding no real customer data.
+func WithoutRedaction() Option {
+	return func(r *Recorder) {
+		r.redactor = nil
+	}
+}
+
+//WithSecrets masks the given strings, such as api tokens, wherever they appear in what's saved.
+func WithSecrets(secrets ...string) Option {
+	return func(r *Recorder) {
+		for _, secret := range secrets {
+			if secret != "" {
+				r.secrets = append(r.secrets, secret)
+			}
+		}
+	}
+}
+
+//WithMatchers replaces the default matchers. A request matches a recorded one if every matcher says so.
+func WithMatchers(matchers ...Matcher) Option {
+	return func(r *Recorder) {
+		r.matchers = matchers
+	}
+}
+
+//WithIgnoredFields drops the given form fields, such as timestamps, before saving and matching.
+func WithIgnoredFields(fields ...string) Option {
+	return func(r *Recorder) {
+		r.ignoredFields = append(r.ignoredFields, fields...)
+	}
+}
+
+//ignoredHeaders aren't saved: the authorization header holds the token, and the rest change on every run.
+var ignoredHeaders = []string{"Authorization", "Idempotency-Key", "Date", "Content-Length"}
+
+//Redacted replaces masked secrets.
+const Redacted = "[REDACTED]"
+
+//Recorder is an http.RoundTripper recording interactions to a cassette file, or replaying them from it. It's safe for concurrent use.
+type Recorder struct {
+	path          string
+	mode          Mode
+	transport     http.RoundTripper
+	redactor      Redactor
+	secrets       []string
+	matchers      []Matcher
+	ignoredFields []string
+
+	mu        sync.Mutex
+	cassette  Cassette
+	used      []bool
+	unmatched []error
+}
+
+//New returns a Recorder for the cassette file at path. When replaying, the file is loaded right away and must exist.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		transport: http.DefaultTransport,
+		redactor:  qvo.DefaultRedactor(),
+		matchers:  DefaultMatchers,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cassette: can't load cassette")
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, errors.Wrapf(err, "cassette: can't decode %s", path)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+//Mode returns the mode the recorder is running in, ModeAuto being resolved.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+//Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+//RoundTrip records or replays a request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	recorded := r.request(req, body)
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	//Send a copy, as RoundTrippers mustn't modify the request.
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.header(resp.Header),
+			Body:       r.redact(string(respBody)),
+		},
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+//replay answers a request with the first unused interaction matching it.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.matches(recorded, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	err := &UnmatchedError{Cassette: r.path, Request: recorded}
+	r.unmatched = append(r.unmatched, err)
+	return nil, err
+}
+
+//matches tells if every matcher matches.
+func (r *Recorder) matches(req, recorded Request) bool {
+	for _, match := range r.matchers {
+		if !match(req, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+//Stop saves the cassette when recording. When replaying, it returns an error if any request was unmatched.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mode == ModeReplay {
+		if len(r.unmatched) > 0 {
+			return errors.Wrapf(r.unmatched[0], "cassette: %d unmatched requests, first one", len(r.unmatched))
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, 0644)
+}
+
+//request builds the recorded form of a request.
+func (r *Recorder) request(req *http.Request, body []byte) Request {
+	form := req.URL.Query()
+	if len(body) > 0 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for k, v := range values {
+				form[k] = append(form[k], v...)
+			}
+		}
+	}
+	for _, field := range r.ignoredFields {
+		form.Del(field)
+	}
+
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Form:   r.redact(form.Encode()),
+		Header: r.header(req.Header),
+	}
+}
+
+//header returns a redacted copy of a header, without the ignored ones.
+func (r *Recorder) header(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		ignored := false
+		for _, name := range ignoredHeaders {
+			if strings.EqualFold(k, name) {
+				ignored = true
+				break
+			}
+		}
+		if ignored {
+			continue
+		}
+		for _, value := range v {
+			out.Add(k, r.redact(value))
+		}
+	}
+	return out
+}
+
+//redact masks the secrets and applies the redactor.
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	if r.redactor != nil {
+		s = r.redactor.Redact(s)
+	}
+	return s
+}
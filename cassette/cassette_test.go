@@ -0,0 +1,112 @@
+package cassette_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/iegomez/qvo-go-client/cassette"
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//run makes the same calls whether recording or replaying.
+func run(c *qvo.Client) (qvo.Customer, []qvo.Customer, error) {
+	ctx := context.Background()
+	customer, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+	if err != nil {
+		return qvo.Customer{}, nil, err
+	}
+	customers, err := c.Customers.List(ctx, 1, 10, map[string]map[string]interface{}{"name": {"like": "%Gómez%"}}, "created_at DESC")
+	return customer, customers, err
+}
+
+func TestRecorder(t *testing.T) {
+	Convey("Given a cassette recorded against a fake server", t, func() {
+		path := filepath.Join(t.TempDir(), "cassettes", "customers.json")
+
+		srv := qvotest.NewServer()
+		rec, err := cassette.New(path, cassette.WithMode(cassette.ModeAuto), cassette.WithSecrets(srv.Token))
+		So(err, ShouldBeNil)
+		So(rec.Mode(), ShouldEqual, cassette.ModeRecord)
+
+		recorded, recordedList, err := run(qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL), qvo.WithTransport(rec)))
+		So(err, ShouldBeNil)
+		So(rec.Stop(), ShouldBeNil)
+		So(rec.Interactions(), ShouldHaveLength, 2)
+		srv.Close()
+
+		Convey("The saved cassette shouldn't hold the token, names or emails", func() {
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(strings.Contains(string(data), srv.Token), ShouldBeFalse)
+			So(strings.Contains(string(data), "test@manglar.cl"), ShouldBeFalse)
+			So(strings.Contains(string(data), "Ignacio"), ShouldBeFalse)
+		})
+
+		Convey("Replaying it should give the same results without network", func() {
+			rep, err := cassette.New(path, cassette.WithMode(cassette.ModeAuto))
+			So(err, ShouldBeNil)
+			So(rep.Mode(), ShouldEqual, cassette.ModeReplay)
+
+			customer, customers, err := run(qvo.NewClient("another-token", true, qvo.WithBaseURL(srv.URL), qvo.WithTransport(rep)))
+			So(err, ShouldBeNil)
+			So(customer.ID, ShouldEqual, recorded.ID)
+			So(customer.CreatedAt, ShouldResemble, recorded.CreatedAt)
+			So(customer.Name, ShouldEqual, cassette.Redacted)
+			So(customer.Email, ShouldEqual, cassette.Redacted)
+			So(customers, ShouldHaveLength, len(recordedList))
+			So(rep.Stop(), ShouldBeNil)
+		})
+
+		Convey("Unmatched requests should fail loudly", func() {
+			rep, err := cassette.New(path)
+			So(err, ShouldBeNil)
+			c := qvo.NewClient("token", true, qvo.WithBaseURL(srv.URL), qvo.WithTransport(rep))
+
+			_, err = qvo.GetCustomer(c, "cus_nope")
+			So(errors.Is(err, cassette.ErrUnmatched), ShouldBeTrue)
+			var unmatched *cassette.UnmatchedError
+			So(errors.As(err, &unmatched), ShouldBeTrue)
+			So(unmatched.Request.Path, ShouldEqual, "/customers/cus_nope")
+			So(rep.Stop(), ShouldNotBeNil)
+		})
+
+		Convey("Matching may ignore the form", func() {
+			rep, err := cassette.New(path, cassette.WithMatchers(cassette.MatchMethod, cassette.MatchPath))
+			So(err, ShouldBeNil)
+			c := qvo.NewClient("token", true, qvo.WithBaseURL(srv.URL), qvo.WithTransport(rep))
+
+			customer, err := qvo.CreateCustomer(c, "Jere Díaz", "test2@manglar.cl")
+			So(err, ShouldBeNil)
+			So(customer.ID, ShouldEqual, recorded.ID)
+		})
+
+		Convey("Replaying a missing cassette should fail", func() {
+			_, err := cassette.New(filepath.Join(t.TempDir(), "missing.json"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Given a cassette recorded without redaction", t, func() {
+		path := filepath.Join(t.TempDir(), "customers.json")
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		rec, err := cassette.New(path, cassette.WithMode(cassette.ModeRecord), cassette.WithSecrets(srv.Token), cassette.WithoutRedaction())
+		So(err, ShouldBeNil)
+		_, _, err = run(qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL), qvo.WithTransport(rec)))
+		So(err, ShouldBeNil)
+		So(rec.Stop(), ShouldBeNil)
+
+		Convey("It should hold names and emails, but not the token", func() {
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(strings.Contains(string(data), "Ignacio"), ShouldBeTrue)
+			So(strings.Contains(string(data), "test@manglar.cl"), ShouldBeTrue)
+			So(strings.Contains(string(data), srv.Token), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package qvo
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+//Change is an attribute that changed in an event.
+type Change struct {
+	Path string      //Dotted JSON path, e.g., "plan.id".
+	Old  interface{} //Value in the event's Previous. Nil if it was null or absent.
+	New  interface{} //Value in the event's Data. Nil if it's null or absent.
+}
+
+//ChangeSet holds an event's changes, sorted by path.
+type ChangeSet []Change
+
+//Changes compares an event's Previous with its Data, as in customer.updated, plan.updated or customer.subscription.updated events.
+//Only the attributes in Previous are compared, at any depth. Nested objects, such as a subscription's plan, are compared attribute by attribute, so changing plans
+//shows up as a change of "plan.id", "plan.price" and so on. Lists are compared as a whole. It's empty for events without previous values.
+func (e Event) Changes() ChangeSet {
+	if e.Previous == nil {
+		return ChangeSet{}
+	}
+	cs := ChangeSet{}
+	for field, old := range *e.Previous {
+		cs = diffValues(cs, field, old, e.Data[field])
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Path < cs[j].Path })
+	return cs
+}
+
+//diffValues appends the changes between two values at path, walking into objects.
+func diffValues(cs ChangeSet, path string, from, to interface{}) ChangeSet {
+	oldMap, oldIsMap := from.(map[string]interface{})
+	newMap, newIsMap := to.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		if !equalValues(from, to) {
+			cs = append(cs, Change{Path: path, Old: from, New: to})
+		}
+		return cs
+	}
+
+	for k, v := range oldMap {
+		cs = diffValues(cs, path+"."+k, v, newMap[k])
+	}
+	return cs
+}
+
+//equalValues tells if two values are the same once encoded as JSON, so an int matches the float64 decoded from the event.
+func equalValues(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
+//Get returns the change at path, if any.
+func (cs ChangeSet) Get(path string) (Change, bool) {
+	for _, c := range cs {
+		if c.Path == path {
+			return c, true
+		}
+	}
+	return Change{}, false
+}
+
+//Paths returns the paths that changed.
+func (cs ChangeSet) Paths() []string {
+	paths := make([]string, len(cs))
+	for i, c := range cs {
+		paths[i] = c.Path
+	}
+	return paths
+}
+
+//Changed tells if the attribute at path changed.
+func (cs ChangeSet) Changed(path string) bool {
+	_, ok := cs.Get(path)
+	return ok
+}
+
+//ChangedFrom tells if the attribute at path changed from value.
+func (cs ChangeSet) ChangedFrom(path string, value interface{}) bool {
+	c, ok := cs.Get(path)
+	return ok && equalValues(c.Old, value)
+}
+
+//ChangedTo tells if the attribute at path changed to value.
+func (cs ChangeSet) ChangedTo(path string, value interface{}) bool {
+	c, ok := cs.Get(path)
+	return ok && equalValues(c.New, value)
+}
+
+//Transitioned tells if the attribute at path changed from one value to another, e.g., cs.Transitioned("status", "active", "retrying").
+func (cs ChangeSet) Transitioned(path string, from, to interface{}) bool {
+	return cs.ChangedFrom(path, from) && cs.ChangedTo(path, to)
+}
+
+//StatusChangedTo tells if the status changed to status, e.g., a subscription becoming "retrying" or a transaction becoming "refunded".
+func (cs ChangeSet) StatusChangedTo(status string) bool {
+	return cs.ChangedTo("status", status)
+}
@@ -0,0 +1,72 @@
+package qvo
+
+import (
+	"testing"
+
+	"github.com/iegomez/qvo-go-client/qvotest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChanges(t *testing.T) {
+	Convey("Given a fake server with an updated customer and subscription", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		c := NewClient(srv.Token, true, WithBaseURL(srv.URL))
+		byType, _, _ := eventsByType(srv, c)
+
+		Convey("A customer's changes should have the old and new values", func() {
+			cs := byType[CustomerUpdated].Changes()
+			So(cs, ShouldResemble, ChangeSet{{Path: "name", Old: "Ignacio Gómez", New: "Ignacio"}})
+			So(cs.Transitioned("name", "Ignacio Gómez", "Ignacio"), ShouldBeTrue)
+			So(cs.Changed("email"), ShouldBeFalse)
+		})
+
+		Convey("Nested objects should be compared attribute by attribute", func() {
+			cs := byType[CustomerSubscriptionUpdated].Changes()
+			So(cs.Paths(), ShouldContain, "plan.id")
+			So(cs.Paths(), ShouldContain, "plan.price")
+			So(cs.Changed("plan.currency"), ShouldBeFalse)
+			So(cs.Transitioned("plan.id", "basic", "pro"), ShouldBeTrue)
+			So(cs.Transitioned("debt", 0, 10000), ShouldBeTrue)
+		})
+
+		Convey("Events without previous values should have no changes", func() {
+			So(byType[CustomerCreated].Changes(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given a subscription that started retrying", t, func() {
+		event := Event{
+			Type:     CustomerSubscriptionUpdated,
+			Data:     map[string]interface{}{"status": "retrying", "plan": map[string]interface{}{"id": "pro"}, "transactions": []interface{}{"trx_1"}},
+			Previous: &map[string]interface{}{"status": "active", "transactions": []interface{}{}},
+		}
+		cs := event.Changes()
+
+		Convey("Status predicates should match the transition", func() {
+			So(cs.Paths(), ShouldResemble, []string{"status", "transactions"})
+			So(cs.StatusChangedTo("retrying"), ShouldBeTrue)
+			So(cs.StatusChangedTo("canceled"), ShouldBeFalse)
+			So(cs.ChangedFrom("status", "active"), ShouldBeTrue)
+			So(cs.Changed("plan.id"), ShouldBeFalse)
+
+			change, ok := cs.Get("transactions")
+			So(ok, ShouldBeTrue)
+			So(change.New, ShouldResemble, []interface{}{"trx_1"})
+		})
+	})
+
+	Convey("Given an event whose previous values hold part of a nested object", t, func() {
+		event := Event{
+			Type:     CustomerSubscriptionUpdated,
+			Data:     map[string]interface{}{"plan": map[string]interface{}{"id": "gold", "name": "Gold", "price": "1000"}},
+			Previous: &map[string]interface{}{"plan": map[string]interface{}{"id": "basic"}},
+		}
+
+		Convey("Only the attributes in it should be compared", func() {
+			cs := event.Changes()
+			So(cs, ShouldResemble, ChangeSet{{Path: "plan.id", Old: "basic", New: "gold"}})
+			So(cs.ChangedFrom("plan.name", nil), ShouldBeFalse)
+		})
+	})
+}
@@ -1,14 +1,11 @@
 package qvo
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -19,9 +16,45 @@ var sandboxURI = "https://playground.qvo.cl"
 var productionURI = "https://api.qvo.cl"
 
 //Client represent the qvo api client. It holds the auth token and sandbox/production mode and offers request methods.
+//It owns a pooled transport and is safe for concurrent use, so a single Client should be shared instead of creating one per call.
 type Client struct {
 	Token     string
 	IsSandbox bool
+
+	//Services for each resource. NewClient sets them, and they may be replaced with fakes in tests.
+	Customers     CustomerService
+	Cards         CardService
+	Plans         PlanService
+	Subscriptions SubscriptionService
+	Transactions  TransactionService
+	Events        EventService
+	Withdrawals   WithdrawalService
+	Webpay        WebpayService
+
+	baseURL    string
+	userAgent  string
+	timeout    time.Duration
+	transport  http.RoundTripper
+	httpClient *http.Client
+
+	transportConfig TransportConfig
+	retryPolicy     RetryPolicy
+
+	autoIdempotencyKeys bool
+	idempotencyStore    IdempotencyStore
+	inflightMu          sync.Mutex
+	inflight            map[string]*inflightCall
+
+	logger        Logger
+	redactor      *Redactor
+	dumpResponses bool
+
+	rateLimiter    *RateLimiter
+	circuitBreaker *CircuitBreaker
+
+	middleware          []Middleware
+	noDefaultMiddleware bool
+	handler             Handler
 }
 
 type errorWrapper struct {
@@ -35,24 +68,59 @@ type qvoError struct {
 	Param   *string `json:"param"`
 }
 
-//Filter implements filter for API queries.
+//Filter implements filter for API queries. A Query is built from them.
 type Filter struct {
 	Attribute string
-	Operator  string
+	Operator  string //One of the Operator constants, e.g., string(OpGte).
 	Value     interface{}
 }
 
-//NewClient initializes the api client with a token and a sandbox/production mode. Default log level is info.
-func NewClient(token string, isSandbox bool) *Client {
-	log.SetLevel(log.InfoLevel)
-	return &Client{
+//NewClient initializes the api client with a token and a sandbox/production mode.
+//Options may be given to override the http client, base url, timeout, user agent and logger.
+//By default the client logs through its own logrus logger at info level, so the global logrus logger is left untouched.
+func NewClient(token string, isSandbox bool, opts ...Option) *Client {
+	c := &Client{
 		Token:     token,
 		IsSandbox: isSandbox,
+		userAgent: DefaultUserAgent,
+		timeout:   DefaultTimeout,
+
+		transportConfig: DefaultTransportConfig(),
+
+		autoIdempotencyKeys: true,
+		idempotencyStore:    NewMemoryIdempotencyStore(DefaultIdempotencyTTL),
+
+		logger:   newDefaultLogger(),
+		redactor: DefaultRedactor(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		if c.transport == nil {
+			c.transport = newTransport(c.transportConfig)
+		}
+		c.httpClient = &http.Client{Transport: c.transport}
 	}
+	c.handler = c.chain()
+
+	c.Customers = &customerService{c: c}
+	c.Cards = &cardService{c: c}
+	c.Plans = &planService{c: c}
+	c.Subscriptions = &subscriptionService{c: c}
+	c.Transactions = &transactionService{c: c}
+	c.Events = &eventService{c: c}
+	c.Withdrawals = &withdrawalService{c: c}
+	c.Webpay = &webpayService{c: c}
+
+	return c
 }
 
-//getURI gets the uri depending on mode.
+//getURI gets the uri depending on mode, unless a base url was given.
 func (c *Client) getURI() string {
+	if c.baseURL != "" {
+		return c.baseURL
+	}
 	if c.IsSandbox {
 		return sandboxURI
 	}
@@ -64,92 +132,155 @@ func (c *Client) getBearer() string {
 	return fmt.Sprintf("Bearer: %s", c.Token)
 }
 
-//SetLogLevel allows to set the log level.
+//SetLogLevel allows to set the log level of the client's default logger.
+//It has no effect on loggers given with WithLogger, whose level should be set on the logger itself.
 func (c *Client) SetLogLevel(logLevel log.Level) {
-	log.SetLevel(logLevel)
+	if l, ok := c.getLogger().(*logrusLogger); ok {
+		l.logger.SetLevel(logLevel)
+	}
 }
 
 //request sends a request to the qvo API and return a json string (as a []byte) or error to the caller so it can get unmarshaled.
-func (c *Client) request(method, endpoint string, values url.Values) ([]byte, error) {
+//The request is bound to ctx, so it's aborted when ctx is canceled or its deadline is exceeded.
+//Transient failures are retried according to the client's retry policy.
+func (c *Client) request(ctx context.Context, method, endpoint string, values url.Values) ([]byte, error) {
 
-	//Set uti and http client.
-	uri := fmt.Sprintf("%s/%s", c.getURI(), endpoint)
-	client := &http.Client{Timeout: 15 * time.Second}
-
-	var req *http.Request
-	var reqErr error
+	if method != "POST" && method != "PUT" && method != "PATCH" && method != "GET" && method != "DELETE" {
+		return []byte{}, errors.New("forbidden method")
+	}
 
-	//Create request.
+	//Mutating requests carry an idempotency key, either supplied by the caller or generated.
+	//Results for caller supplied keys are recorded so repeated calls don't hit the API again.
 	if method == "POST" || method == "PUT" || method == "PATCH" {
-		req, reqErr = http.NewRequest(method, uri, strings.NewReader(values.Encode()))
-		if reqErr != nil {
-			log.Errorf("req error: %v\n", reqErr)
-			return []byte{}, reqErr
+		if key := idempotencyKeyFrom(ctx); key != "" {
+			if c.idempotencyStore == nil {
+				return c.send(ctx, method, endpoint, values, key)
+			}
+			return c.idempotent(ctx, key, method, endpoint, values, func() ([]byte, error) {
+				return c.send(ctx, method, endpoint, values, key)
+			})
 		}
-		req.Header.Set("Content-Length", strconv.Itoa(len(values.Encode())))
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	} else if method == "GET" || method == "DELETE" {
-		req, reqErr = http.NewRequest(method, uri, nil)
-		if reqErr != nil {
-			log.Errorf("req error: %v\n", reqErr)
-			return []byte{}, reqErr
+		if c.autoIdempotencyKeys {
+			return c.send(ctx, method, endpoint, values, newIdempotencyKey())
 		}
-		req.URL.RawQuery = values.Encode()
-	} else {
-		return []byte{}, errors.New("forbidden method")
 	}
 
-	req.Header.Set("authorization", c.getBearer())
+	return c.send(ctx, method, endpoint, values, "")
 
-	//Uncomment to dump request.
+}
 
-	dr, err := httputil.DumpRequestOut(req, true)
-	if err != nil {
-		log.Errorf("req dump error: %s", err)
-		return nil, err
-	}
-	log.Debugf("\ndump req: %s\n", []byte(dr))
+//send makes a request, retrying it according to the client's retry policy. Every attempt waits for the rate limiter, if any.
+func (c *Client) send(ctx context.Context, method, endpoint string, values url.Values, idempotencyKey string) ([]byte, error) {
 
-	//Post the request.
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Errorf("error: %v\n", err)
-		return []byte{}, err
+	start := time.Now()
+	policy := c.retryPolicy
+	for attempt := 1; ; attempt++ {
+		if c.rateLimiter != nil {
+			if err := c.rateLimiter.Wait(ctx, method); err != nil {
+				setResponseMeta(ctx, nil, attempt-1, start)
+				return []byte{}, err
+			}
+		}
+
+		var done func(outcome)
+		if c.circuitBreaker != nil {
+			var err error
+			if done, err = c.circuitBreaker.allow(); err != nil {
+				c.log(LevelWarn, "qvo request rejected by circuit breaker", Fields{"method": method, "endpoint": endpoint, "attempt": attempt, "error": err})
+				setResponseMeta(ctx, nil, attempt-1, start)
+				return []byte{}, err
+			}
+		}
+
+		resp, err := c.do(ctx, method, endpoint, values, idempotencyKey, attempt)
+		if done != nil {
+			done(breakerOutcome(ctx, err))
+		}
+		if c.rateLimiter != nil && resp != nil {
+			c.rateLimiter.Observe(method, resp.StatusCode, resp.Header)
+		}
+		if err == nil {
+			setResponseMeta(ctx, resp, attempt, start)
+			return resp.Body, nil
+		}
+
+		if attempt >= policy.MaxAttempts || !policy.allowsMethod(method, idempotencyKey != "") || !isRetryable(ctx, err) {
+			setResponseMeta(ctx, resp, attempt, start)
+			return []byte{}, err
+		}
+
+		//Honor Retry-After when it asks for a longer wait than our backoff.
+		var header http.Header
+		if resp != nil {
+			header = resp.Header
+		}
+		wait := policy.backoff(attempt)
+		if retryAfter := parseRetryAfter(header); retryAfter > wait {
+			if policy.MaxRetryAfter > 0 && retryAfter > policy.MaxRetryAfter {
+				setResponseMeta(ctx, resp, attempt, start)
+				return []byte{}, err
+			}
+			wait = retryAfter
+		}
+
+		info := RetryInfo{
+			Method:   method,
+			Endpoint: endpoint,
+			Attempt:  attempt,
+			Err:      err,
+			Wait:     wait,
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			info.StatusCode = apiErr.StatusCode
+		}
+		c.log(LevelWarn, "retrying qvo request", Fields{"method": method, "endpoint": endpoint, "attempt": attempt, "status": info.StatusCode, "wait": wait, "error": err})
+		if policy.OnRetry != nil {
+			policy.OnRetry(info)
+		}
+
+		if sleepErr := sleep(ctx, wait); sleepErr != nil {
+			setResponseMeta(ctx, resp, attempt, start)
+			return []byte{}, sleepErr
+		}
 	}
 
-	//Uncomment to dump response.
+}
 
-	/*dresp, err := httputil.DumpResponse(resp, true)
-	if err != nil {
-		log.Errorf("resp dump error: %s", err)
-		return nil, err
+//do makes a single attempt of a request through the client's middleware chain.
+//The response is returned along with the error for non 2xx responses, and whenever a response was received.
+func (c *Client) do(ctx context.Context, method, endpoint string, values url.Values, idempotencyKey string, attempt int) (*Response, error) {
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
 	}
-	log.Infof("\ndump resp: %s\n", []byte(dresp))*/
 
-	//read body.
-	body, bErr := ioutil.ReadAll(resp.Body)
-	defer resp.Body.Close()
+	call := &Call{
+		Method:   method,
+		Endpoint: endpoint,
+		Values:   values,
+		Header:   http.Header{},
+		Attempt:  attempt,
+	}
+	if idempotencyKey != "" {
+		call.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
 
-	if bErr != nil {
-		log.Errorf("read error: %v\n", bErr)
-		return []byte{}, bErr
+	resp, err := c.getHandler()(ctx, call)
+	if err != nil {
+		return resp, err
+	}
+	if resp == nil {
+		return nil, ErrNoResponse
 	}
 
 	//If we get an error code, check the qvo standard error.
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		qvoMessage := ""
-		qvoParam := ""
-		typeStr := ""
-		qvoErr := qvoError{Type: &typeStr, Message: &qvoMessage, Param: &qvoParam}
-		errorWrap := errorWrapper{Error: qvoErr}
-		unErr := json.Unmarshal(body, &errorWrap)
-		if unErr != nil {
-			log.Errorf("unmarshal error: %v\n", unErr)
-			return []byte{}, unErr
-		}
-		return []byte{}, errors.Errorf("QVO error\tstatus: %d\ttype: %s\tmessage: %s\tparam: %s\t\n", resp.StatusCode, *qvoErr.Type, *qvoErr.Message, *qvoErr.Param)
+		return resp, newAPIError(resp.StatusCode, resp.Body)
 	}
 
-	return body, nil
+	return resp, nil
 
 }
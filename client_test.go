@@ -0,0 +1,131 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientOptions(t *testing.T) {
+	Convey("Given a local server standing in for QVO", t, func() {
+
+		var gotPath, gotAgent, gotAuth string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			gotAgent = r.Header.Get("User-Agent")
+			gotAuth = r.Header.Get("authorization")
+			if r.URL.Path == "/customers/slow" {
+				select {
+				case <-time.After(time.Second):
+				case <-r.Context().Done():
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "cus_1", "name": "Ignacio Gómez", "email": "test@manglar.cl"}`))
+		}))
+		defer srv.Close()
+
+		Convey("A client with a base url and user agent should use them", func() {
+			c := NewClient("token", true, WithBaseURL(srv.URL+"/"), WithUserAgent("billing/1.0"))
+
+			customer, err := GetCustomer(c, "cus_1")
+			So(err, ShouldBeNil)
+			So(customer.Email, ShouldEqual, "test@manglar.cl")
+			So(gotPath, ShouldEqual, "/customers/cus_1")
+			So(gotAgent, ShouldEqual, "billing/1.0")
+			So(gotAuth, ShouldEqual, "Bearer: token")
+		})
+
+		Convey("A given http client should be used", func() {
+			used := false
+			hc := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				used = true
+				return http.DefaultTransport.RoundTrip(r)
+			})}
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithHTTPClient(hc))
+
+			_, err := GetCustomer(c, "cus_1")
+			So(err, ShouldBeNil)
+			So(used, ShouldBeTrue)
+			So(gotAgent, ShouldEqual, DefaultUserAgent)
+		})
+
+		Convey("The client timeout should abort slow requests", func() {
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithTimeout(50*time.Millisecond))
+
+			_, err := GetCustomer(c, "slow")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("A canceled context should abort the request", func() {
+			c := NewClient("token", true, WithBaseURL(srv.URL))
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+			}()
+
+			start := time.Now()
+			_, err := GetCustomerWithContext(ctx, c, "slow")
+			So(err, ShouldNotBeNil)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+//newBenchServer returns a local server answering every request with a customer.
+func newBenchServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "cus_1", "name": "Ignacio Gómez", "email": "test@manglar.cl"}`))
+	}))
+}
+
+func benchmarkGetCustomer(b *testing.B, c *Client) {
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := GetCustomer(c, "cus_1"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+//BenchmarkPerRequestClient mimics the old behavior: a new http.Client with Go's default transport settings for every request.
+func BenchmarkPerRequestClient(b *testing.B) {
+	srv := newBenchServer()
+	defer srv.Close()
+
+	legacy := http.DefaultTransport.(*http.Transport).Clone()
+	defer legacy.CloseIdleConnections()
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		client := &http.Client{Timeout: DefaultTimeout, Transport: legacy}
+		return client.Do(r)
+	})
+
+	benchmarkGetCustomer(b, NewClient("token", true, WithBaseURL(srv.URL), WithTransport(rt)))
+}
+
+//BenchmarkSharedTransport uses the pooled transport owned by the client.
+func BenchmarkSharedTransport(b *testing.B) {
+	srv := newBenchServer()
+	defer srv.Close()
+
+	c := NewClient("token", true, WithBaseURL(srv.URL))
+	defer c.CloseIdleConnections()
+
+	benchmarkGetCustomer(b, c)
+}
@@ -1,14 +1,9 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/url"
-	"strconv"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 //Customer struct to represent a qvo customer object.
@@ -24,135 +19,129 @@ type Customer struct {
 	UpdatedAt            time.Time      `json:"updated_at"`
 }
 
-//CreateCustomer creates a customer at the QVO account.
-func CreateCustomer(c *Client, name, email string) (Customer, error) {
-
-	form := url.Values{}
-	form.Add("name", name)
-	form.Add("email", email)
+//CustomerService handles customers. A Client exposes it as its Customers field, which may be replaced with a fake in tests.
+type CustomerService interface {
+	Create(ctx context.Context, name, email string) (Customer, error)
+	Get(ctx context.Context, id string) (Customer, error)
+	Update(ctx context.Context, id, name, email, defaultPaymentMethodID string) (Customer, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Customer, error)
+	ListByQuery(ctx context.Context, q *Query) ([]Customer, error)
+}
 
-	body, err := c.request("POST", "customers", form)
-	if err != nil {
-		return Customer{}, err
-	}
+//customerResource is the customers endpoint.
+var customerResource = resource[Customer]{path: "customers", idKey: "customer_id"}
 
-	var customer Customer
-	err = json.Unmarshal(body, &customer)
+//customerService implements CustomerService through a Client.
+type customerService struct {
+	c *Client
+}
 
-	if err != nil {
-		return Customer{}, err
+//customers returns the client's CustomerService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) customers() CustomerService {
+	if c.Customers != nil {
+		return c.Customers
 	}
+	return &customerService{c: c}
+}
 
-	return customer, nil
-
+//CreateCustomer calls CreateCustomerWithContext with a background context.
+func CreateCustomer(c *Client, name, email string) (Customer, error) {
+	return CreateCustomerWithContext(context.Background(), c, name, email)
 }
 
-//GetCustomer retrieves a customer given its id.
-func GetCustomer(c *Client, id string) (Customer, error) {
+//CreateCustomerWithContext calls Create on the client's CustomerService.
+func CreateCustomerWithContext(ctx context.Context, c *Client, name, email string) (Customer, error) {
+	return c.customers().Create(ctx, name, email)
+}
 
-	endpoint := fmt.Sprintf("customers/%s", id)
+//Create creates a customer at the QVO account.
+func (s *customerService) Create(ctx context.Context, name, email string) (Customer, error) {
 
 	form := url.Values{}
-	form.Add("customer_id", id)
-
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Customer{}, err
-	}
+	form.Add("name", name)
+	form.Add("email", email)
 
-	var customer Customer
-	err = json.Unmarshal(body, &customer)
+	return customerResource.create(ctx, s.c, form)
+}
 
-	if err != nil {
-		return Customer{}, err
-	}
+//GetCustomer calls GetCustomerWithContext with a background context.
+func GetCustomer(c *Client, id string) (Customer, error) {
+	return GetCustomerWithContext(context.Background(), c, id)
+}
 
-	return customer, nil
+//GetCustomerWithContext calls Get on the client's CustomerService.
+func GetCustomerWithContext(ctx context.Context, c *Client, id string) (Customer, error) {
+	return c.customers().Get(ctx, id)
+}
 
+//Get retrieves a customer given its id.
+func (s *customerService) Get(ctx context.Context, id string) (Customer, error) {
+	return customerResource.get(ctx, s.c, id)
 }
 
-//UpdateCustomer updates a customer given its id.
+//UpdateCustomer calls UpdateCustomerWithContext with a background context.
 func UpdateCustomer(c *Client, id, name, email, defaultPaymentMethodID string) (Customer, error) {
+	return UpdateCustomerWithContext(context.Background(), c, id, name, email, defaultPaymentMethodID)
+}
 
-	endpoint := fmt.Sprintf("customers/%s", id)
+//UpdateCustomerWithContext calls Update on the client's CustomerService.
+func UpdateCustomerWithContext(ctx context.Context, c *Client, id, name, email, defaultPaymentMethodID string) (Customer, error) {
+	return c.customers().Update(ctx, id, name, email, defaultPaymentMethodID)
+}
+
+//Update updates a customer given its id.
+func (s *customerService) Update(ctx context.Context, id, name, email, defaultPaymentMethodID string) (Customer, error) {
 
 	form := url.Values{}
-	form.Add("customer_id", id)
 	form.Add("name", name)
 	form.Add("email", email)
 	form.Add("default_payment_method_id", defaultPaymentMethodID)
 
-	body, err := c.request("PUT", endpoint, form)
-	if err != nil {
-		return Customer{}, err
-	}
-
-	var customer Customer
-	err = json.Unmarshal(body, &customer)
-
-	if err != nil {
-		return Customer{}, err
-	}
-
-	return customer, nil
-
+	return customerResource.update(ctx, s.c, id, form)
 }
 
-//DeleteCustomer deletes a customer given its id.
+//DeleteCustomer calls DeleteCustomerWithContext with a background context.
 func DeleteCustomer(c *Client, id string) error {
+	return DeleteCustomerWithContext(context.Background(), c, id)
+}
 
-	endpoint := fmt.Sprintf("customers/%s", id)
-
-	form := url.Values{}
-	form.Add("customer_id", id)
-
-	_, err := c.request("DELETE", endpoint, form)
-	if err != nil {
-		return err
-	}
-
-	return nil
+//DeleteCustomerWithContext calls Delete on the client's CustomerService.
+func DeleteCustomerWithContext(ctx context.Context, c *Client, id string) error {
+	return c.customers().Delete(ctx, id)
+}
 
+//Delete deletes a customer given its id.
+func (s *customerService) Delete(ctx context.Context, id string) error {
+	return customerResource.delete(ctx, s.c, id, nil)
 }
 
-//ListCustomers retrieves a list of customers with given pages, filters and order.
+//ListCustomers calls ListCustomersWithContext with a background context.
 func ListCustomers(c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Customer, error) {
+	return ListCustomersWithContext(context.Background(), c, page, perPage, where, orderBy)
+}
 
-	var customers = make([]Customer, 0)
-
-	form := url.Values{}
-	if page > 0 && perPage > 0 {
-		form.Add("page", strconv.Itoa(page))
-		form.Add("per_page", strconv.Itoa(perPage))
-	}
-
-	if len(where) > 0 {
-		jBytes, err := json.Marshal(where)
-		if err != nil {
-			log.Errorf("errored at where: %s", err)
-			return customers, err
-		}
-		form.Add("where", string(jBytes))
-	}
-
-	if orderBy != "" {
-		form.Add("order_by", orderBy)
-	}
-
-	body, err := c.request("GET", "customers", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return customers, err
-	}
+//ListCustomersWithContext calls List on the client's CustomerService.
+func ListCustomersWithContext(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Customer, error) {
+	return c.customers().List(ctx, page, perPage, where, orderBy)
+}
 
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&customers)
+//List retrieves a list of customers with given pages, filters and order.
+func (s *customerService) List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Customer, error) {
+	return customerResource.list(ctx, s.c, page, perPage, where, orderBy)
+}
 
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return customers, err
-	}
+//ListCustomersByQuery calls ListCustomersByQueryWithContext with a background context.
+func ListCustomersByQuery(c *Client, q *Query) ([]Customer, error) {
+	return ListCustomersByQueryWithContext(context.Background(), c, q)
+}
 
-	return customers, nil
+//ListCustomersByQueryWithContext calls ListByQuery on the client's CustomerService.
+func ListCustomersByQueryWithContext(ctx context.Context, c *Client, q *Query) ([]Customer, error) {
+	return c.customers().ListByQuery(ctx, q)
+}
 
+//ListByQuery retrieves a list of customers matching a query, after checking its fields are customer fields.
+func (s *customerService) ListByQuery(ctx context.Context, q *Query) ([]Customer, error) {
+	return customerResource.listByQuery(ctx, s.c, q)
 }
@@ -4,16 +4,23 @@ import (
 	"os"
 	"testing"
 
+	"github.com/iegomez/qvo-go-client/qvotest"
 	log "github.com/sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestCustomer(t *testing.T) {
 	log.SetLevel(log.DebugLevel)
-	//Use test token and playground
+	//Use test token and playground, or the fake server if there's no token.
 	token := os.Getenv("QVO_TEST_TOKEN")
+	var opts []Option
+	if token == "" {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		token, opts = srv.Token, append(opts, WithBaseURL(srv.URL))
+	}
 	Convey("Given valid token a client should be created", t, func() {
-		c := NewClient(token, true)
+		c := NewClient(token, true, opts...)
 		//Set log level at debug.
 		c.SetLogLevel(log.DebugLevel)
 
@@ -0,0 +1,80 @@
+package qvo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//Sentinel errors to check an *APIError against with errors.Is.
+var (
+	ErrInvalidRequest = errors.New("qvo: invalid request")
+	ErrUnauthorized   = errors.New("qvo: unauthorized")
+	ErrNotFound       = errors.New("qvo: not found")
+	ErrConflict       = errors.New("qvo: conflict")
+	ErrRateLimited    = errors.New("qvo: rate limited")
+	ErrServer         = errors.New("qvo: server error")
+)
+
+//APIError is returned for every non 2xx response from the API.
+//Type, Message and Param are taken from QVO's standard error; they're empty when the body isn't one (e.g., an HTML 502 page), so Body holds the raw response.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+	Param      string
+	Body       []byte
+}
+
+//Error keeps the format of the string errors the client returned before APIError existed.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("QVO error\tstatus: %d\ttype: %s\tmessage: %s\tparam: %s\t\n", e.StatusCode, e.Type, e.Message, e.Param)
+}
+
+//Is makes errors.Is(err, ErrNotFound) and the like work by matching the status code against the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+//newAPIError builds an APIError from a non 2xx response. If the body isn't a standard qvo error, the status text is used as message.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       body,
+	}
+
+	var errorWrap errorWrapper
+	if err := json.Unmarshal(body, &errorWrap); err != nil || (errorWrap.Error.Type == nil && errorWrap.Error.Message == nil) {
+		apiErr.Message = strings.ToLower(http.StatusText(statusCode))
+		return apiErr
+	}
+
+	if errorWrap.Error.Type != nil {
+		apiErr.Type = *errorWrap.Error.Type
+	}
+	if errorWrap.Error.Message != nil {
+		apiErr.Message = *errorWrap.Error.Message
+	}
+	if errorWrap.Error.Param != nil {
+		apiErr.Param = *errorWrap.Error.Param
+	}
+
+	return apiErr
+}
@@ -0,0 +1,63 @@
+package qvo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPIError(t *testing.T) {
+	Convey("Given a local server returning errors", t, func() {
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/customers/missing":
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Customer not found", "param": "customer_id"}}`))
+			case "/customers":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Email has already been taken", "param": "email"}}`))
+			default:
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(http.StatusBadGateway)
+				w.Write([]byte(`<html><body><h1>502 Bad Gateway</h1></body></html>`))
+			}
+		}))
+		defer srv.Close()
+
+		c := NewClient("token", true, WithBaseURL(srv.URL))
+
+		Convey("A 404 should be an APIError matching ErrNotFound", func() {
+			_, err := GetCustomer(c, "missing")
+			So(errors.Is(err, ErrNotFound), ShouldBeTrue)
+			So(errors.Is(err, ErrInvalidRequest), ShouldBeFalse)
+
+			var apiErr *APIError
+			So(errors.As(err, &apiErr), ShouldBeTrue)
+			So(apiErr.StatusCode, ShouldEqual, http.StatusNotFound)
+			So(apiErr.Type, ShouldEqual, "invalid_request_error")
+			So(apiErr.Message, ShouldEqual, "Customer not found")
+			So(apiErr.Param, ShouldEqual, "customer_id")
+		})
+
+		Convey("A bad param should match ErrInvalidRequest", func() {
+			_, err := CreateCustomer(c, "Ignacio Gómez", "test@manglar.cl")
+			So(errors.Is(err, ErrInvalidRequest), ShouldBeTrue)
+		})
+
+		Convey("A non JSON body should still be a typed error", func() {
+			_, err := GetPlan(c, "plan")
+			So(errors.Is(err, ErrServer), ShouldBeTrue)
+
+			var apiErr *APIError
+			So(errors.As(err, &apiErr), ShouldBeTrue)
+			So(apiErr.StatusCode, ShouldEqual, http.StatusBadGateway)
+			So(apiErr.Message, ShouldEqual, "bad gateway")
+			So(string(apiErr.Body), ShouldContainSubstring, "502 Bad Gateway")
+		})
+
+	})
+}
@@ -1,14 +1,8 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net/url"
-	"strconv"
+	"context"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 //Event types
@@ -39,68 +33,70 @@ type Event struct {
 	CreatedAt time.Time               `json:"created_at"`
 }
 
-//GetEvent retrieves a event given its id.
-func GetEvent(c *Client, id string) (Event, error) {
+//EventService handles events. A Client exposes it as its Events field, which may be replaced with a fake in tests.
+type EventService interface {
+	Get(ctx context.Context, id string) (Event, error)
+	List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Event, error)
+	ListByQuery(ctx context.Context, q *Query) ([]Event, error)
+}
 
-	endpoint := fmt.Sprintf("events/%s", id)
+//eventResource is the events endpoint.
+var eventResource = resource[Event]{path: "events", idKey: "event_id"}
 
-	form := url.Values{}
-	form.Add("event_id", id)
+//eventService implements EventService through a Client.
+type eventService struct {
+	c *Client
+}
 
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Event{}, err
+//events returns the client's EventService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) events() EventService {
+	if c.Events != nil {
+		return c.Events
 	}
+	return &eventService{c: c}
+}
 
-	var event Event
-	err = json.Unmarshal(body, &event)
-
-	if err != nil {
-		return Event{}, err
-	}
+//GetEvent calls GetEventWithContext with a background context.
+func GetEvent(c *Client, id string) (Event, error) {
+	return GetEventWithContext(context.Background(), c, id)
+}
 
-	return event, nil
+//GetEventWithContext calls Get on the client's EventService.
+func GetEventWithContext(ctx context.Context, c *Client, id string) (Event, error) {
+	return c.events().Get(ctx, id)
+}
 
+//Get retrieves a event given its id.
+func (s *eventService) Get(ctx context.Context, id string) (Event, error) {
+	return eventResource.get(ctx, s.c, id)
 }
 
-//ListEvents retrieves a list of events with given pages, filters and order.
+//ListEvents calls ListEventsWithContext with a background context.
 func ListEvents(c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Event, error) {
+	return ListEventsWithContext(context.Background(), c, page, perPage, where, orderBy)
+}
 
-	var events = make([]Event, 0)
-
-	form := url.Values{}
-	if page > 0 && perPage > 0 {
-		form.Add("page", strconv.Itoa(page))
-		form.Add("per_page", strconv.Itoa(perPage))
-	}
-
-	if len(where) > 0 {
-		jBytes, err := json.Marshal(where)
-		if err != nil {
-			log.Errorf("errored at where: %s", err)
-			return events, err
-		}
-		form.Add("where", string(jBytes))
-	}
-
-	if orderBy != "" {
-		form.Add("order_by", orderBy)
-	}
-
-	body, err := c.request("GET", "events", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return events, err
-	}
+//ListEventsWithContext calls List on the client's EventService.
+func ListEventsWithContext(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Event, error) {
+	return c.events().List(ctx, page, perPage, where, orderBy)
+}
 
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&events)
+//List retrieves a list of events with given pages, filters and order.
+func (s *eventService) List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Event, error) {
+	return eventResource.list(ctx, s.c, page, perPage, where, orderBy)
+}
 
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return events, err
-	}
+//ListEventsByQuery calls ListEventsByQueryWithContext with a background context.
+func ListEventsByQuery(c *Client, q *Query) ([]Event, error) {
+	return ListEventsByQueryWithContext(context.Background(), c, q)
+}
 
-	return events, nil
+//ListEventsByQueryWithContext calls ListByQuery on the client's EventService.
+func ListEventsByQueryWithContext(ctx context.Context, c *Client, q *Query) ([]Event, error) {
+	return c.events().ListByQuery(ctx, q)
+}
 
+//ListByQuery retrieves a list of events matching a query, after checking its fields are event fields.
+func (s *eventService) ListByQuery(ctx context.Context, q *Query) ([]Event, error) {
+	return eventResource.listByQuery(ctx, s.c, q)
 }
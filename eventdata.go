@@ -0,0 +1,142 @@
+package qvo
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//Errors returned when decoding an event's data.
+var (
+	ErrUnknownEventType = errors.New("qvo: unknown event type")
+	ErrEventDataType    = errors.New("qvo: event data is of a different type")
+)
+
+//EventObject returns the kind of object an event type is about: "card" for customer.card.*, "subscription" for customer.subscription.*,
+//"customer" for the other customer.* types, and "plan" or "transaction" for plan.* and transaction.*. It's empty for unknown types.
+func EventObject(eventType string) string {
+	switch {
+	case strings.HasPrefix(eventType, "customer.card."):
+		return "card"
+	case strings.HasPrefix(eventType, "customer.subscription."):
+		return "subscription"
+	case strings.HasPrefix(eventType, "customer."):
+		return "customer"
+	case strings.HasPrefix(eventType, "plan."):
+		return "plan"
+	case strings.HasPrefix(eventType, "transaction."):
+		return "transaction"
+	}
+	return ""
+}
+
+//eventModels maps each object kind to its model.
+var eventModels = map[string]reflect.Type{
+	"customer":     reflect.TypeOf(Customer{}),
+	"card":         reflect.TypeOf(Card{}),
+	"subscription": reflect.TypeOf(Subscription{}),
+	"plan":         reflect.TypeOf(Plan{}),
+	"transaction":  reflect.TypeOf(Transaction{}),
+}
+
+//Object decodes the event's data into the model its type is about, i.e., a Customer, Card, Subscription, Plan or Transaction, to use in a type switch:
+//
+//	switch obj := obj.(type) {
+//	case qvo.Subscription:
+//		...
+//	}
+//
+//It returns an error matching ErrUnknownEventType for other types.
+func (e Event) Object() (interface{}, error) {
+	model, err := e.model()
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(model)
+	if err := decodeEventMap(e.Data, v.Interface()); err != nil {
+		return nil, errors.Wrapf(err, "can't decode %s data", e.Type)
+	}
+	return v.Elem().Interface(), nil
+}
+
+//model returns the model for the event's type.
+func (e Event) model() (reflect.Type, error) {
+	model, ok := eventModels[EventObject(e.Type)]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownEventType, "event %s has type %q", e.ID, e.Type)
+	}
+	return model, nil
+}
+
+//DecodeData decodes an event's data into T, which must be the model its type is about, e.g.:
+//
+//	subscription, err := qvo.DecodeData[qvo.Subscription](event)
+//
+//It returns an error matching ErrUnknownEventType for unknown types, and one matching ErrEventDataType if T is a different model.
+func DecodeData[T any](e Event) (T, error) {
+	var v T
+	if err := checkModel[T](e); err != nil {
+		return v, err
+	}
+	if err := decodeEventMap(e.Data, &v); err != nil {
+		return v, errors.Wrapf(err, "can't decode %s data", e.Type)
+	}
+	return v, nil
+}
+
+//Partial is an object of which only some attributes are known, such as the old values in an event's Previous.
+type Partial[T any] struct {
+	Value  T        //Attributes not in Fields are zero.
+	Fields []string //The known attributes' JSON names, sorted.
+}
+
+//Has tells if an attribute, given by its JSON name, is known.
+func (p Partial[T]) Has(field string) bool {
+	i := sort.SearchStrings(p.Fields, field)
+	return i < len(p.Fields) && p.Fields[i] == field
+}
+
+//DecodePrevious decodes an event's Previous, the old values of the attributes that changed, into T, which must be the model its type is about.
+//It returns nil without an error if the event has no previous values. Errors are those of DecodeData.
+func DecodePrevious[T any](e Event) (*Partial[T], error) {
+	if err := checkModel[T](e); err != nil {
+		return nil, err
+	}
+	if e.Previous == nil {
+		return nil, nil
+	}
+
+	p := &Partial[T]{Fields: make([]string, 0, len(*e.Previous))}
+	for field := range *e.Previous {
+		p.Fields = append(p.Fields, field)
+	}
+	sort.Strings(p.Fields)
+	if err := decodeEventMap(*e.Previous, &p.Value); err != nil {
+		return nil, errors.Wrapf(err, "can't decode %s previous values", e.Type)
+	}
+	return p, nil
+}
+
+//checkModel checks T is the model for the event's type.
+func checkModel[T any](e Event) error {
+	model, err := e.model()
+	if err != nil {
+		return err
+	}
+	if got := reflect.TypeOf((*T)(nil)).Elem(); got != model {
+		return errors.Wrapf(ErrEventDataType, "event %s of type %q holds a %s, not a %s", e.ID, e.Type, model.Name(), got)
+	}
+	return nil
+}
+
+//decodeEventMap decodes a map into v through JSON, as the map was decoded from it.
+func decodeEventMap(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
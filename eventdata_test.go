@@ -0,0 +1,102 @@
+package qvo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//eventsByType makes a customer named "Ignacio Gómez", adds a card, renames the customer to "Ignacio", subscribes it to plan "basic" and upgrades it to "pro".
+//It returns the resulting events by type, along with the card's id and the subscription.
+func eventsByType(srv *qvotest.Server, c *Client) (byType map[string]Event, cardID string, subscription Subscription) {
+	ctx := context.Background()
+
+	customer, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+	So(err, ShouldBeNil)
+	cardID, err = srv.AddCard(customer.ID)
+	So(err, ShouldBeNil)
+	_, err = c.Customers.Update(ctx, customer.ID, "Ignacio", "", "")
+	So(err, ShouldBeNil)
+	for _, plan := range []Plan{
+		{ID: "basic", Name: "Basic", Price: "9990", Currency: "CLP", Interval: "month", IntervalCount: 1},
+		{ID: "pro", Name: "Pro", Price: "19990", Currency: "CLP", Interval: "month", IntervalCount: 1},
+	} {
+		_, err := c.Plans.Create(ctx, plan)
+		So(err, ShouldBeNil)
+	}
+	subscription, err = c.Subscriptions.Create(ctx, customer.ID, "basic", "", 0, 0, nil)
+	So(err, ShouldBeNil)
+	_, err = c.Subscriptions.Update(ctx, subscription.ID, "pro")
+	So(err, ShouldBeNil)
+
+	events, err := c.Events.List(ctx, 0, 0, nil, "")
+	So(err, ShouldBeNil)
+	byType = make(map[string]Event)
+	for _, event := range events {
+		byType[event.Type] = event
+	}
+	return byType, cardID, subscription
+}
+
+func TestEventData(t *testing.T) {
+	Convey("Given a fake server with some customer, card and subscription events", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		c := NewClient(srv.Token, true, WithBaseURL(srv.URL))
+		byType, cardID, subscription := eventsByType(srv, c)
+
+		Convey("Data should be decoded into the model of the event's type", func() {
+			obj, err := byType[CustomerUpdated].Object()
+			So(err, ShouldBeNil)
+			So(obj, ShouldHaveSameTypeAs, Customer{})
+			So(obj.(Customer).Name, ShouldEqual, "Ignacio")
+
+			obj, err = byType[CustomerCardCreated].Object()
+			So(err, ShouldBeNil)
+			So(obj.(Card).ID, ShouldEqual, cardID)
+
+			updated, err := DecodeData[Subscription](byType[CustomerSubscriptionUpdated])
+			So(err, ShouldBeNil)
+			So(updated.ID, ShouldEqual, subscription.ID)
+			So(updated.Plan.ID, ShouldEqual, "pro")
+			So(updated.CreatedAt.IsZero(), ShouldBeFalse)
+
+			plan, err := DecodeData[Plan](byType[PlanCreated])
+			So(err, ShouldBeNil)
+			So(plan.Price, ShouldEqual, "19990")
+		})
+
+		Convey("Previous should be decoded into a partial model", func() {
+			previous, err := DecodePrevious[Customer](byType[CustomerUpdated])
+			So(err, ShouldBeNil)
+			So(previous.Value.Name, ShouldEqual, "Ignacio Gómez")
+			So(previous.Fields, ShouldResemble, []string{"name"})
+			So(previous.Has("name"), ShouldBeTrue)
+			So(previous.Has("email"), ShouldBeFalse)
+
+			subPrevious, err := DecodePrevious[Subscription](byType[CustomerSubscriptionUpdated])
+			So(err, ShouldBeNil)
+			So(subPrevious.Value.Plan.ID, ShouldEqual, "basic")
+			So(subPrevious.Has("plan"), ShouldBeTrue)
+
+			none, err := DecodePrevious[Customer](byType[CustomerCreated])
+			So(err, ShouldBeNil)
+			So(none, ShouldBeNil)
+		})
+
+		Convey("Unknown types and wrong models should be reported", func() {
+			_, err := Event{ID: "evt_1", Type: "withdrawal.created"}.Object()
+			So(errors.Is(err, ErrUnknownEventType), ShouldBeTrue)
+			_, err = DecodePrevious[Customer](Event{ID: "evt_1", Type: "withdrawal.created"})
+			So(errors.Is(err, ErrUnknownEventType), ShouldBeTrue)
+
+			_, err = DecodeData[Customer](byType[CustomerCardCreated])
+			So(errors.Is(err, ErrEventDataType), ShouldBeTrue)
+			_, err = DecodePrevious[Plan](byType[CustomerUpdated])
+			So(errors.Is(err, ErrEventDataType), ShouldBeTrue)
+		})
+	})
+}
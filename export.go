@@ -0,0 +1,253 @@
+package qvo
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//ExportFormat is the format objects are exported in.
+type ExportFormat int
+
+//Export formats.
+const (
+	FormatCSV   ExportFormat = iota //A header row and a row per object, with the selected columns.
+	FormatJSONL                     //A JSON object per line, as the client decodes it.
+)
+
+//ExportProgress tells how far an export got. It's returned even when the export fails, so it can be resumed with ResumeExport.
+type ExportProgress struct {
+	Page int //Last page fully written.
+	Rows int //Objects written so far.
+}
+
+//ExportOption configures an export.
+type ExportOption func(*exportOptions)
+
+type exportOptions struct {
+	columns []string
+	resume  ExportProgress
+	onPage  func(ExportProgress) error
+}
+
+//WithColumns selects the CSV columns, as dotted paths of json names, e.g., "payment.fee" or "customer.email".
+//Columns holding objects or lists are written as JSON. By default every top level field is exported.
+func WithColumns(columns ...string) ExportOption {
+	return func(o *exportOptions) {
+		o.columns = columns
+	}
+}
+
+//ResumeExport continues an interrupted export after the last page it wrote, appending to what was written, so no CSV header is written again.
+//The query should order by a field that doesn't change, such as created_at, for pages to hold the same objects.
+func ResumeExport(progress ExportProgress) ExportOption {
+	return func(o *exportOptions) {
+		o.resume = progress
+	}
+}
+
+//OnExportPage calls f after each page is written, e.g., to save the progress. An error from f stops the export.
+func OnExportPage(f func(ExportProgress) error) ExportOption {
+	return func(o *exportOptions) {
+		o.onPage = f
+	}
+}
+
+//flusher is implemented by buffered writers, such as *bufio.Writer.
+type flusher interface {
+	Flush() error
+}
+
+//export streams every page matching q to w. Each page is encoded in memory before it's written, so a failed page isn't partially written.
+func export[T any](ctx context.Context, w io.Writer, format ExportFormat, q *Query, list func(ctx context.Context, q *Query) ([]T, error), opts []ExportOption) (ExportProgress, error) {
+	var o exportOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	progress := o.resume
+
+	var zero T
+	t := reflect.TypeOf(zero)
+	var buf bytes.Buffer
+	var csvw *csv.Writer
+	var enc *json.Encoder
+	switch format {
+	case FormatCSV:
+		if len(o.columns) == 0 {
+			o.columns = columnsOf(t)
+		}
+		for _, column := range o.columns {
+			if _, ok := fieldType(t, column); !ok {
+				return progress, errors.Errorf("qvo: %s has no field %q to export", strings.ToLower(t.Name()), column)
+			}
+		}
+		csvw = csv.NewWriter(&buf)
+		if progress.Page == 0 {
+			if err := csvw.Write(o.columns); err != nil {
+				return progress, err
+			}
+		}
+	case FormatJSONL:
+		enc = json.NewEncoder(&buf)
+	default:
+		return progress, errors.Errorf("qvo: unknown export format %d", format)
+	}
+
+	q = q.clone()
+	perPage := defaultPageSize
+	if q.perPage > 0 {
+		perPage = q.perPage
+	}
+
+	for page := progress.Page + 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		var meta ResponseMeta
+		objects, err := list(WithResponseMeta(ctx, &meta), q.Page(page, perPage))
+		if err != nil {
+			return progress, err
+		}
+
+		for _, object := range objects {
+			if enc != nil {
+				err = enc.Encode(object)
+			} else {
+				err = writeRow(csvw, object, o.columns)
+			}
+			if err != nil {
+				return progress, err
+			}
+		}
+
+		if csvw != nil {
+			csvw.Flush()
+			if err := csvw.Error(); err != nil {
+				return progress, err
+			}
+		}
+		if _, err := buf.WriteTo(w); err != nil {
+			return progress, err
+		}
+		if f, ok := w.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return progress, err
+			}
+		}
+
+		progress.Page = page
+		progress.Rows += len(objects)
+		if o.onPage != nil {
+			if err := o.onPage(progress); err != nil {
+				return progress, err
+			}
+		}
+
+		if len(objects) < perPage || (meta.Pagination.TotalPages > 0 && page >= meta.Pagination.TotalPages) {
+			return progress, nil
+		}
+	}
+}
+
+//columnsOf returns the json names of a type's top level fields.
+func columnsOf(t reflect.Type) []string {
+	t = elem(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "-" {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+//writeRow writes an object's columns as a CSV row.
+func writeRow(w *csv.Writer, object interface{}, columns []string) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var flat map[string]interface{}
+	if err := dec.Decode(&flat); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i], err = cell(flat, column)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Write(row)
+}
+
+//cell formats the value at a dotted path. Missing and null values are empty, and objects and lists are JSON.
+func cell(object map[string]interface{}, path string) (string, error) {
+	var v interface{} = object
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		v = m[name]
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+//ExportCustomers streams every customer matching q to w.
+func ExportCustomers(ctx context.Context, c *Client, w io.Writer, format ExportFormat, q *Query, opts ...ExportOption) (ExportProgress, error) {
+	return export(ctx, w, format, q, c.customers().ListByQuery, opts)
+}
+
+//ExportPlans streams every plan matching q to w.
+func ExportPlans(ctx context.Context, c *Client, w io.Writer, format ExportFormat, q *Query, opts ...ExportOption) (ExportProgress, error) {
+	return export(ctx, w, format, q, c.plans().ListByQuery, opts)
+}
+
+//ExportSubscriptions streams every subscription matching q to w.
+func ExportSubscriptions(ctx context.Context, c *Client, w io.Writer, format ExportFormat, q *Query, opts ...ExportOption) (ExportProgress, error) {
+	return export(ctx, w, format, q, c.subscriptions().ListByQuery, opts)
+}
+
+//ExportTransactions streams every transaction matching q to w.
+func ExportTransactions(ctx context.Context, c *Client, w io.Writer, format ExportFormat, q *Query, opts ...ExportOption) (ExportProgress, error) {
+	return export(ctx, w, format, q, c.transactions().ListByQuery, opts)
+}
+
+//ExportEvents streams every event matching q to w.
+func ExportEvents(ctx context.Context, c *Client, w io.Writer, format ExportFormat, q *Query, opts ...ExportOption) (ExportProgress, error) {
+	return export(ctx, w, format, q, c.events().ListByQuery, opts)
+}
+
+//ExportWithdrawals streams every withdrawal matching q to w.
+func ExportWithdrawals(ctx context.Context, c *Client, w io.Writer, format ExportFormat, q *Query, opts ...ExportOption) (ExportProgress, error) {
+	return export(ctx, w, format, q, c.withdrawals().ListByQuery, opts)
+}
@@ -0,0 +1,101 @@
+package qvo
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExport(t *testing.T) {
+	Convey("Given a fake server with five paid transactions", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		c := NewClient(srv.Token, true, WithBaseURL(srv.URL))
+		ctx := context.Background()
+
+		customer, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+		So(err, ShouldBeNil)
+		cardID, err := srv.AddCard(customer.ID)
+		So(err, ShouldBeNil)
+		for i := 1; i <= 5; i++ {
+			_, err := c.Cards.Charge(ctx, customer.ID, cardID, fmt.Sprintf("fee %d", i), int64(1000*i))
+			So(err, ShouldBeNil)
+		}
+		q := NewQuery().OrderBy("created_at", Asc).Page(1, 2)
+
+		Convey("They should be exported as CSV with flattened columns", func() {
+			var buf bytes.Buffer
+			progress, err := ExportTransactions(ctx, c, &buf, FormatCSV, q, WithColumns("id", "amount", "payment.fee", "customer.email", "refund"))
+			So(err, ShouldBeNil)
+			So(progress, ShouldResemble, ExportProgress{Page: 3, Rows: 5})
+
+			rows, err := csv.NewReader(&buf).ReadAll()
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 6)
+			So(rows[0], ShouldResemble, []string{"id", "amount", "payment.fee", "customer.email", "refund"})
+			So(rows[1][1], ShouldEqual, "1000")
+			So(rows[1][2], ShouldNotBeEmpty)
+			So(rows[1][3], ShouldEqual, "test@manglar.cl")
+			So(rows[1][4], ShouldEqual, "")
+		})
+
+		Convey("Every top level field should be exported by default", func() {
+			var buf bytes.Buffer
+			_, err := ExportCustomers(ctx, c, &buf, FormatCSV, nil)
+			So(err, ShouldBeNil)
+			header := strings.SplitN(buf.String(), "\n", 2)[0]
+			So(header, ShouldEqual, "id,default_payment_method,name,email,subscriptions,cards,transactions,created_at,updated_at")
+		})
+
+		Convey("They should be exported as JSON lines", func() {
+			var buf bytes.Buffer
+			_, err := ExportTransactions(ctx, c, &buf, FormatJSONL, q)
+			So(err, ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(lines, ShouldHaveLength, 5)
+			var transaction Transaction
+			So(json.Unmarshal([]byte(lines[4]), &transaction), ShouldBeNil)
+			So(transaction.Amount, ShouldEqual, 5000)
+		})
+
+		Convey("An interrupted export should resume after its last page", func() {
+			var buf bytes.Buffer
+			stop := errors.New("interrupted")
+			progress, err := ExportTransactions(ctx, c, &buf, FormatCSV, q, WithColumns("id"), OnExportPage(func(p ExportProgress) error {
+				if p.Page == 2 {
+					return stop
+				}
+				return nil
+			}))
+			So(err, ShouldEqual, stop)
+			So(progress, ShouldResemble, ExportProgress{Page: 2, Rows: 4})
+
+			srv.FailNext(1, "GET", "transactions", 400)
+			_, err = ExportTransactions(ctx, c, &buf, FormatCSV, q, WithColumns("id"), ResumeExport(progress))
+			So(err, ShouldNotBeNil)
+
+			progress, err = ExportTransactions(ctx, c, &buf, FormatCSV, q, WithColumns("id"), ResumeExport(progress))
+			So(err, ShouldBeNil)
+			So(progress, ShouldResemble, ExportProgress{Page: 3, Rows: 5})
+
+			rows, err := csv.NewReader(&buf).ReadAll()
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 6)
+		})
+
+		Convey("Unknown columns should be rejected before any request", func() {
+			before := srv.Count("GET", "transactions")
+			_, err := ExportTransactions(ctx, c, &bytes.Buffer{}, FormatCSV, q, WithColumns("payment.tip"))
+			So(err, ShouldNotBeNil)
+			So(srv.Count("GET", "transactions"), ShouldEqual, before)
+		})
+	})
+}
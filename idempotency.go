@@ -0,0 +1,206 @@
+package qvo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//IdempotencyKeyHeader is the header carrying a request's idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+//DefaultIdempotencyTTL is how long the default store keeps the results of requests made with a caller supplied key.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+//ErrIdempotencyKeyReused is returned when a caller supplied key is used again for a different method, endpoint or parameters than the ones it was first used with.
+var ErrIdempotencyKeyReused = errors.New("qvo: idempotency key reused for a different request")
+
+type contextKey int
+
+const (
+	idempotencyKeyContextKey contextKey = iota
+)
+
+//WithIdempotencyKey returns a copy of ctx which makes mutating requests carry the given idempotency key instead of a generated one.
+//The same key is sent on every retry of the request, and, while the client's idempotency store holds it, repeating the call returns the first result instead of hitting the API again.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+//idempotencyKeyFrom returns the idempotency key set on ctx, if any.
+func idempotencyKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey).(string)
+	return key
+}
+
+//newIdempotencyKey generates a random key in UUID v4 format.
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		//crypto/rand doesn't fail on supported platforms, but fall back to a time based key just in case.
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+//hashValues returns the hex encoded SHA-256 of a request's encoded form, so requests reusing a key may be compared without keeping their parameters.
+func hashValues(values url.Values) string {
+	sum := sha256.Sum256([]byte(values.Encode()))
+	return hex.EncodeToString(sum[:])
+}
+
+//WithAutoIdempotencyKeys enables or disables generating an idempotency key for mutating requests without a caller supplied one. It's enabled by default.
+func WithAutoIdempotencyKeys(enabled bool) Option {
+	return func(c *Client) {
+		c.autoIdempotencyKeys = enabled
+	}
+}
+
+//WithIdempotencyStore sets the store recording the results of requests made with a caller supplied key. A nil store disables it.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(c *Client) {
+		c.idempotencyStore = store
+	}
+}
+
+//IdempotencyRecord is the result of a successful request made with a caller supplied idempotency key.
+type IdempotencyRecord struct {
+	Method    string
+	Endpoint  string
+	BodyHash  string //Hex encoded SHA-256 of the request's encoded form.
+	Body      []byte
+	CreatedAt time.Time
+}
+
+//IdempotencyStore records the results of requests by idempotency key. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	Get(key string) (IdempotencyRecord, bool)
+	Put(key string, record IdempotencyRecord)
+}
+
+//MemoryIdempotencyStore is an in-process IdempotencyStore whose records expire after a ttl.
+type MemoryIdempotencyStore struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+//NewMemoryIdempotencyStore returns an empty store keeping records for the given ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		records: make(map[string]IdempotencyRecord),
+	}
+}
+
+//Get returns the record for key, if there's one and it hasn't expired.
+func (s *MemoryIdempotencyStore) Get(key string) (IdempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	if !ok || s.expired(record, time.Now()) {
+		return IdempotencyRecord{}, false
+	}
+	return record, true
+}
+
+//Put records the result for key, dropping expired records along the way.
+func (s *MemoryIdempotencyStore) Put(key string, record IdempotencyRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, r := range s.records {
+		if s.expired(r, now) {
+			delete(s.records, k)
+		}
+	}
+	s.records[key] = record
+}
+
+func (s *MemoryIdempotencyStore) expired(record IdempotencyRecord, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(record.CreatedAt) > s.ttl
+}
+
+//inflightCall is a request being made with a caller supplied key, which concurrent calls with the same key wait for.
+type inflightCall struct {
+	method   string
+	endpoint string
+	bodyHash string
+	done     chan struct{}
+	body     []byte
+	err      error
+}
+
+//idempotent makes sure a request with a caller supplied key reaches the API at most once while its result is recorded:
+//it returns the recorded result if any, waits for a concurrent call with the same key, or runs send and records its result on success.
+func (c *Client) idempotent(ctx context.Context, key, method, endpoint string, values url.Values, send func() ([]byte, error)) ([]byte, error) {
+	bodyHash := hashValues(values)
+	var call *inflightCall
+	for call == nil {
+		if record, ok := c.idempotencyStore.Get(key); ok {
+			if record.Method != method || record.Endpoint != endpoint || record.BodyHash != bodyHash {
+				return []byte{}, ErrIdempotencyKeyReused
+			}
+			if meta := responseMetaFrom(ctx); meta != nil {
+				*meta = ResponseMeta{Replayed: true}
+			}
+			return record.Body, nil
+		}
+
+		c.inflightMu.Lock()
+		if c.inflight == nil {
+			c.inflight = make(map[string]*inflightCall)
+		}
+		other, ok := c.inflight[key]
+		if !ok {
+			call = &inflightCall{method: method, endpoint: endpoint, bodyHash: bodyHash, done: make(chan struct{})}
+			c.inflight[key] = call
+			c.inflightMu.Unlock()
+			break
+		}
+		c.inflightMu.Unlock()
+		if other.method != method || other.endpoint != endpoint || other.bodyHash != bodyHash {
+			return []byte{}, ErrIdempotencyKeyReused
+		}
+
+		//Someone else is making this request. Wait for it and use its result, or try again if it failed.
+		select {
+		case <-other.done:
+			if other.err == nil {
+				if meta := responseMetaFrom(ctx); meta != nil {
+					*meta = ResponseMeta{Replayed: true}
+				}
+				return other.body, nil
+			}
+		case <-ctx.Done():
+			return []byte{}, ctx.Err()
+		}
+	}
+
+	call.body, call.err = send()
+	if call.err == nil {
+		c.idempotencyStore.Put(key, IdempotencyRecord{
+			Method:    method,
+			Endpoint:  endpoint,
+			BodyHash:  bodyHash,
+			Body:      call.body,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	c.inflightMu.Lock()
+	delete(c.inflight, key)
+	c.inflightMu.Unlock()
+	close(call.done)
+
+	return call.body, call.err
+}
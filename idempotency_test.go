@@ -0,0 +1,118 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIdempotency(t *testing.T) {
+	Convey("Given a local server recording idempotency keys", t, func() {
+
+		var hits int32
+		var mu sync.Mutex
+		var keys []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&hits, 1)
+			mu.Lock()
+			keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			if n == 1 {
+				w.Write([]byte(`{"id": "trx_1", "amount": 1000, "status": "successful"}`))
+				return
+			}
+			w.Write([]byte(`{"id": "trx_2", "amount": 1000, "status": "successful"}`))
+		}))
+		defer srv.Close()
+
+		c := NewClient("token", true, WithBaseURL(srv.URL))
+
+		Convey("Mutating requests should get a generated key and reads none", func() {
+			_, err := ChargeCard(c, "cus_1", "card_1", "test", 1000)
+			So(err, ShouldBeNil)
+			_, err = ChargeCard(c, "cus_1", "card_1", "test", 1000)
+			So(err, ShouldBeNil)
+			_, err = GetTransaction(c, "trx_1")
+			So(err, ShouldBeNil)
+
+			So(keys, ShouldHaveLength, 3)
+			So(keys[0], ShouldHaveLength, 36)
+			So(keys[1], ShouldHaveLength, 36)
+			So(keys[0], ShouldNotEqual, keys[1])
+			So(keys[2], ShouldBeEmpty)
+		})
+
+		Convey("Repeating a call with the same key should return the first result", func() {
+			ctx := WithIdempotencyKey(context.Background(), "charge-1")
+
+			first, err := ChargeCardWithContext(ctx, c, "cus_1", "card_1", "test", 1000)
+			So(err, ShouldBeNil)
+			second, err := ChargeCardWithContext(ctx, c, "cus_1", "card_1", "test", 1000)
+			So(err, ShouldBeNil)
+
+			So(second.ID, ShouldEqual, first.ID)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+			So(keys, ShouldResemble, []string{"charge-1"})
+
+			Convey("But using it for a different request should fail", func() {
+				_, err := RefundTransactionWithContext(ctx, c, "trx_1")
+				So(errors.Is(err, ErrIdempotencyKeyReused), ShouldBeTrue)
+			})
+
+			Convey("Or charging a different amount with it", func() {
+				_, err := ChargeCardWithContext(ctx, c, "cus_1", "card_1", "test", 99999)
+				So(errors.Is(err, ErrIdempotencyKeyReused), ShouldBeTrue)
+				So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+			})
+		})
+
+		Convey("Concurrent calls with the same key should hit the API once", func() {
+			ctx := WithIdempotencyKey(context.Background(), "charge-2")
+
+			var wg sync.WaitGroup
+			ids := make([]string, 5)
+			for i := range ids {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					transaction, err := ChargeCardWithContext(ctx, c, "cus_1", "card_1", "test", 1000)
+					if err == nil {
+						ids[i] = transaction.ID
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+			for _, id := range ids {
+				So(id, ShouldEqual, "trx_1")
+			}
+		})
+
+		Convey("A concurrent call using the key for a different request should fail", func() {
+			ctx := WithIdempotencyKey(context.Background(), "charge-3")
+
+			charged := make(chan error, 1)
+			go func() {
+				_, err := ChargeCardWithContext(ctx, c, "cus_1", "card_1", "test", 1000)
+				charged <- err
+			}()
+			for atomic.LoadInt32(&hits) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			_, err := RefundTransactionWithContext(ctx, c, "trx_1")
+			So(errors.Is(err, ErrIdempotencyKeyReused), ShouldBeTrue)
+			So(<-charged, ShouldBeNil)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+		})
+
+	})
+}
@@ -0,0 +1,229 @@
+package qvo
+
+import (
+	"context"
+)
+
+//defaultPageSize is the page size iterators use when their query doesn't set one.
+const defaultPageSize = 50
+
+//IterOption configures an Iter.
+type IterOption func(*iterOptions)
+
+type iterOptions struct {
+	maxItems int
+	prefetch int
+}
+
+//WithMaxItems stops the iterator after n items. 0, the default, walks every page.
+func WithMaxItems(n int) IterOption {
+	return func(o *iterOptions) {
+		o.maxItems = n
+	}
+}
+
+//WithPrefetch fetches up to n pages concurrently ahead of the one being walked, which speeds up large exports.
+//Pages are still yielded in order. By default pages are fetched one at a time when needed.
+func WithPrefetch(n int) IterOption {
+	return func(o *iterOptions) {
+		o.prefetch = n
+	}
+}
+
+//pageResult is a fetched page.
+type pageResult[T any] struct {
+	items      []T
+	totalPages int
+	err        error
+}
+
+//Iter lazily walks every page of a list, stopping after the last one:
+//
+//	it := qvo.IterCustomers(ctx, c, qvo.NewQuery().OrderBy("created_at", qvo.Asc))
+//	for it.Next() {
+//		customer := it.Current()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+//An Iter isn't safe for concurrent use.
+type Iter[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	list   func(ctx context.Context, q *Query) ([]T, error)
+	query  *Query
+	opts   iterOptions
+
+	perPage   int
+	nextPage  int //Next page to fetch.
+	lastPage  int //Last page to fetch, once known.
+	pending   []chan pageResult[T]
+	exhausted bool
+
+	items   []T
+	current T
+	seen    int
+	done    bool
+	err     error
+}
+
+//newIter returns an iterator listing pages with list. The query's page and page size, if set, are where and how it starts.
+func newIter[T any](ctx context.Context, q *Query, list func(ctx context.Context, q *Query) ([]T, error), opts []IterOption) *Iter[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &Iter[T]{
+		ctx:      ctx,
+		cancel:   cancel,
+		list:     list,
+		query:    q.clone(),
+		perPage:  defaultPageSize,
+		nextPage: 1,
+	}
+	for _, opt := range opts {
+		opt(&it.opts)
+	}
+	if it.query.perPage > 0 {
+		it.perPage = it.query.perPage
+	}
+	if it.query.page > 0 {
+		it.nextPage = it.query.page
+	}
+	return it
+}
+
+//Next advances to the next item, fetching the next page if needed. It returns false when there are no more items or an error happened.
+func (it *Iter[T]) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.opts.maxItems > 0 && it.seen >= it.opts.maxItems {
+		it.stop(nil)
+		return false
+	}
+
+	for len(it.items) == 0 {
+		if it.exhausted {
+			it.stop(nil)
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.stop(err)
+			return false
+		}
+		result := it.fetchNext()
+		if result.err != nil {
+			it.stop(result.err)
+			return false
+		}
+		it.items = result.items
+	}
+
+	it.current, it.items = it.items[0], it.items[1:]
+	it.seen++
+	return true
+}
+
+//Current returns the item Next advanced to.
+func (it *Iter[T]) Current() T {
+	return it.current
+}
+
+//Err returns the error that stopped the iterator, if any. It should be checked once Next returns false.
+func (it *Iter[T]) Err() error {
+	return it.err
+}
+
+//Close stops the iterator and cancels any prefetched page. It's only needed when the iterator isn't walked to the end.
+func (it *Iter[T]) Close() {
+	it.stop(nil)
+}
+
+//stop ends the iteration with err, if it hasn't ended yet.
+func (it *Iter[T]) stop(err error) {
+	if it.done {
+		return
+	}
+	it.done, it.err = true, err
+	it.items = nil
+	it.cancel()
+}
+
+//fetchNext returns the next page, starting prefetches as allowed, and finds out whether it's the last one.
+func (it *Iter[T]) fetchNext() pageResult[T] {
+	page := it.nextPage - len(it.pending)
+	if it.opts.prefetch <= 1 {
+		it.nextPage++
+		return it.finish(page, it.fetch(page))
+	}
+
+	for len(it.pending) < it.opts.prefetch && (it.lastPage == 0 || it.nextPage <= it.lastPage) {
+		ch := make(chan pageResult[T], 1)
+		go func(page int) {
+			ch <- it.fetch(page)
+		}(it.nextPage)
+		it.pending = append(it.pending, ch)
+		it.nextPage++
+	}
+
+	ch := it.pending[0]
+	it.pending = it.pending[1:]
+	select {
+	case result := <-ch:
+		return it.finish(page, result)
+	case <-it.ctx.Done():
+		return pageResult[T]{err: it.ctx.Err()}
+	}
+}
+
+//finish marks the iterator as exhausted after the last page: a short one, or the one the API says is last.
+func (it *Iter[T]) finish(page int, result pageResult[T]) pageResult[T] {
+	if result.err != nil {
+		return result
+	}
+	if result.totalPages > 0 {
+		it.lastPage = result.totalPages
+	}
+	if len(result.items) < it.perPage || (it.lastPage > 0 && page >= it.lastPage) {
+		it.exhausted = true
+	}
+	return result
+}
+
+//fetch lists a page.
+func (it *Iter[T]) fetch(page int) pageResult[T] {
+	var meta ResponseMeta
+	q := it.query.clone().Page(page, it.perPage)
+	items, err := it.list(WithResponseMeta(it.ctx, &meta), q)
+	return pageResult[T]{items: items, totalPages: meta.Pagination.TotalPages, err: err}
+}
+
+//IterCustomers returns an iterator over every customer matching q.
+func IterCustomers(ctx context.Context, c *Client, q *Query, opts ...IterOption) *Iter[Customer] {
+	return newIter(ctx, q, c.customers().ListByQuery, opts)
+}
+
+//IterPlans returns an iterator over every plan matching q.
+func IterPlans(ctx context.Context, c *Client, q *Query, opts ...IterOption) *Iter[Plan] {
+	return newIter(ctx, q, c.plans().ListByQuery, opts)
+}
+
+//IterSubscriptions returns an iterator over every subscription matching q.
+func IterSubscriptions(ctx context.Context, c *Client, q *Query, opts ...IterOption) *Iter[Subscription] {
+	return newIter(ctx, q, c.subscriptions().ListByQuery, opts)
+}
+
+//IterTransactions returns an iterator over every transaction matching q.
+func IterTransactions(ctx context.Context, c *Client, q *Query, opts ...IterOption) *Iter[Transaction] {
+	return newIter(ctx, q, c.transactions().ListByQuery, opts)
+}
+
+//IterEvents returns an iterator over every event matching q.
+func IterEvents(ctx context.Context, c *Client, q *Query, opts ...IterOption) *Iter[Event] {
+	return newIter(ctx, q, c.events().ListByQuery, opts)
+}
+
+//IterWithdrawals returns an iterator over every withdrawal matching q.
+func IterWithdrawals(ctx context.Context, c *Client, q *Query, opts ...IterOption) *Iter[Withdrawal] {
+	return newIter(ctx, q, c.withdrawals().ListByQuery, opts)
+}
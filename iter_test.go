@@ -0,0 +1,125 @@
+package qvo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//pagedCustomers is a CustomerService paging a fixed list, without pagination headers.
+type pagedCustomers struct {
+	CustomerService
+	customers []Customer
+	calls     int
+}
+
+func (p *pagedCustomers) ListByQuery(ctx context.Context, q *Query) ([]Customer, error) {
+	p.calls++
+	page, perPage, _, _, err := q.Params()
+	if err != nil {
+		return nil, err
+	}
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(p.customers) {
+		start = len(p.customers)
+	}
+	if end > len(p.customers) {
+		end = len(p.customers)
+	}
+	return p.customers[start:end], nil
+}
+
+//collect walks an iterator.
+func collect(it *Iter[Customer]) ([]string, error) {
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Current().ID)
+	}
+	return ids, it.Err()
+}
+
+func TestIter(t *testing.T) {
+	Convey("Given a fake server with seven customers", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		c := NewClient(srv.Token, true, WithBaseURL(srv.URL))
+		ctx := context.Background()
+
+		var want []string
+		for i := 0; i < 7; i++ {
+			customer, err := c.Customers.Create(ctx, fmt.Sprintf("Customer %d", i), fmt.Sprintf("test%d@manglar.cl", i))
+			So(err, ShouldBeNil)
+			want = append(want, customer.ID)
+		}
+		q := NewQuery().OrderBy("created_at", Asc).Page(1, 3)
+
+		Convey("The iterator should walk every page and stop at the last one", func() {
+			ids, err := collect(IterCustomers(ctx, c, q))
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, want)
+			So(srv.Count("GET", "customers"), ShouldEqual, 3)
+		})
+
+		Convey("It should stop after the max items", func() {
+			ids, err := collect(IterCustomers(ctx, c, q, WithMaxItems(4)))
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, want[:4])
+			So(srv.Count("GET", "customers"), ShouldEqual, 2)
+		})
+
+		Convey("Prefetching should keep the order", func() {
+			ids, err := collect(IterCustomers(ctx, c, NewQuery().OrderBy("created_at", Asc).Page(1, 2), WithPrefetch(3)))
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, want)
+			So(srv.Count("GET", "customers"), ShouldEqual, 4)
+		})
+
+		Convey("It should start at the query's page", func() {
+			ids, err := collect(IterCustomers(ctx, c, NewQuery().OrderBy("created_at", Asc).Page(2, 3)))
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, want[3:])
+		})
+
+		Convey("Errors should be surfaced at the end", func() {
+			ids, err := collect(IterCustomers(ctx, c, NewQuery().Eq("created", "2018-03-01")))
+			So(ids, ShouldBeEmpty)
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+		})
+
+		Convey("A cancelled context should stop it", func() {
+			cctx, cancel := context.WithCancel(ctx)
+			it := IterCustomers(cctx, c, q)
+			So(it.Next(), ShouldBeTrue)
+			cancel()
+			for it.Next() {
+			}
+			So(errors.Is(it.Err(), context.Canceled), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a service without pagination headers", t, func() {
+		c := NewClient("token", true, WithBaseURL("http://127.0.0.1:0"))
+		paged := &pagedCustomers{}
+		for i := 0; i < 6; i++ {
+			paged.customers = append(paged.customers, Customer{ID: fmt.Sprintf("cus_%d", i)})
+		}
+		c.Customers = paged
+
+		Convey("The iterator should stop after an empty or short page", func() {
+			ids, err := collect(IterCustomers(context.Background(), c, NewQuery().Page(1, 3)))
+			So(err, ShouldBeNil)
+			So(ids, ShouldHaveLength, 6)
+			So(paged.calls, ShouldEqual, 3)
+
+			paged.calls = 0
+			ids, err = collect(IterCustomers(context.Background(), c, NewQuery().Page(1, 4)))
+			So(err, ShouldBeNil)
+			So(ids, ShouldHaveLength, 6)
+			So(paged.calls, ShouldEqual, 2)
+		})
+	})
+}
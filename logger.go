@@ -0,0 +1,127 @@
+package qvo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+//LogLevel is the severity of a client log entry.
+type LogLevel int
+
+//Log levels, from the most verbose to the least.
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+//Fields are the structured fields attached to a log entry, e.g., method, endpoint, status, duration and attempt for requests.
+type Fields map[string]interface{}
+
+//Logger is what the client logs through. Adapters for logrus and log/slog are provided, but any logger may be plugged in with WithLogger.
+type Logger interface {
+	//Enabled tells if entries at the given level are logged, so the client may skip expensive work (e.g., request dumps) otherwise.
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, msg string, fields Fields)
+}
+
+//WithLogger sets the client's logger. By default, a client logs through its own logrus logger at info level.
+func WithLogger(logger Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+//logrusLogger adapts a logrus logger to Logger.
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+//NewLogrusLogger returns a Logger writing to the given logrus logger.
+func NewLogrusLogger(logger *logrus.Logger) Logger {
+	return &logrusLogger{logger: logger}
+}
+
+//Enabled checks the level against the logrus logger's one.
+func (l *logrusLogger) Enabled(level LogLevel) bool {
+	return l.logger.IsLevelEnabled(l.logrusLevel(level))
+}
+
+//Log writes an entry with the given fields.
+func (l *logrusLogger) Log(level LogLevel, msg string, fields Fields) {
+	l.logger.WithFields(logrus.Fields(fields)).Log(l.logrusLevel(level), msg)
+}
+
+func (l *logrusLogger) logrusLevel(level LogLevel) logrus.Level {
+	switch level {
+	case LevelDebug:
+		return logrus.DebugLevel
+	case LevelInfo:
+		return logrus.InfoLevel
+	case LevelWarn:
+		return logrus.WarnLevel
+	}
+	return logrus.ErrorLevel
+}
+
+//slogLogger adapts a log/slog logger to Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+//NewSlogLogger returns a Logger writing to the given slog logger.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger}
+}
+
+//Enabled checks the level against the slog logger's handler.
+func (l *slogLogger) Enabled(level LogLevel) bool {
+	return l.logger.Enabled(context.Background(), l.slogLevel(level))
+}
+
+//Log writes a record with the given fields as attributes.
+func (l *slogLogger) Log(level LogLevel, msg string, fields Fields) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	l.logger.LogAttrs(context.Background(), l.slogLevel(level), msg, attrs...)
+}
+
+func (l *slogLogger) slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+//newDefaultLogger returns a logrus logger of its own at info level, so the global logrus logger is never touched.
+func newDefaultLogger() *logrusLogger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	return &logrusLogger{logger: logger}
+}
+
+//defaultLogger is used by clients that weren't created with NewClient.
+var defaultLogger = newDefaultLogger()
+
+//getLogger returns the client's logger, or the shared default one for clients not created with NewClient.
+func (c *Client) getLogger() Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return defaultLogger
+}
+
+//log writes an entry through the client's logger.
+func (c *Client) log(level LogLevel, msg string, fields Fields) {
+	c.getLogger().Log(level, msg, fields)
+}
@@ -0,0 +1,62 @@
+package qvo
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogger(t *testing.T) {
+	Convey("Given a local server standing in for QVO", t, func() {
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id": "pln_1", "name": "Test plan"}`))
+		}))
+		defer srv.Close()
+
+		Convey("Creating a client and setting its level shouldn't touch the global logrus logger", func() {
+			logrus.SetLevel(logrus.WarnLevel)
+			defer logrus.SetLevel(logrus.InfoLevel)
+
+			c := NewClient("token", true)
+			c.SetLogLevel(logrus.DebugLevel)
+			So(logrus.GetLevel(), ShouldEqual, logrus.WarnLevel)
+			So(c.getLogger().Enabled(LevelDebug), ShouldBeTrue)
+		})
+
+		Convey("A slog logger should get requests with structured fields", func() {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithLogger(NewSlogLogger(logger)))
+
+			_, err := GetPlan(c, "pln_1")
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, `"msg":"qvo request"`)
+			So(buf.String(), ShouldContainSubstring, `"method":"GET"`)
+			So(buf.String(), ShouldContainSubstring, `"endpoint":"plans/pln_1"`)
+			So(buf.String(), ShouldContainSubstring, `"status":200`)
+			So(buf.String(), ShouldContainSubstring, `"attempt":1`)
+			So(buf.String(), ShouldContainSubstring, `"duration":`)
+		})
+
+		Convey("A logrus logger should get requests with structured fields", func() {
+			var buf bytes.Buffer
+			logger := logrus.New()
+			logger.SetOutput(&buf)
+			logger.SetLevel(logrus.DebugLevel)
+			logger.SetFormatter(&logrus.JSONFormatter{})
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithLogger(NewLogrusLogger(logger)))
+
+			_, err := GetPlan(c, "pln_1")
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, `"endpoint":"plans/pln_1"`)
+			So(buf.String(), ShouldContainSubstring, `"status":200`)
+		})
+
+	})
+}
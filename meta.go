@@ -0,0 +1,117 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//ResponseMeta holds what the API answered besides the decoded result: status, headers, timing and the rate limit and pagination info found in them.
+//Pass a pointer to WithResponseMeta to get it filled for a call.
+type ResponseMeta struct {
+	StatusCode int
+	Header     http.Header
+	RequestID  string        //Request id sent by the API, if any, useful for support tickets.
+	Latency    time.Duration //Round-trip time of the last attempt.
+	Duration   time.Duration //Total time of the call, including retries and backoff.
+	Attempts   int
+	Replayed   bool //The result was taken from the idempotency store, so no request was made.
+	RateLimit  RateLimit
+	Pagination Pagination
+}
+
+//RateLimit is the rate limit info found in the response headers. Fields are zero if the API didn't send them.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+//Pagination is the pagination info found in the response headers. Fields are zero if the API didn't send them.
+type Pagination struct {
+	Total      int
+	TotalPages int
+	Page       int
+	PerPage    int
+}
+
+//requestIDHeaders are checked in order for the request id.
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "X-Correlation-Id"}
+
+const responseMetaContextKey contextKey = idempotencyKeyContextKey + 1
+
+//WithResponseMeta returns a copy of ctx which makes the call fill meta once it's done, whether it succeeded or not.
+//
+//	var meta qvo.ResponseMeta
+//	customers, err := qvo.ListCustomersWithContext(qvo.WithResponseMeta(ctx, &meta), c, 1, 50, where, "")
+//	log.Printf("request %s took %s", meta.RequestID, meta.Latency)
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaContextKey, meta)
+}
+
+//responseMetaFrom returns the meta set on ctx, if any.
+func responseMetaFrom(ctx context.Context) *ResponseMeta {
+	meta, _ := ctx.Value(responseMetaContextKey).(*ResponseMeta)
+	return meta
+}
+
+//setResponseMeta fills the meta set on ctx, if any, with the last response of a call.
+func setResponseMeta(ctx context.Context, resp *Response, attempts int, start time.Time) {
+	meta := responseMetaFrom(ctx)
+	if meta == nil {
+		return
+	}
+
+	*meta = ResponseMeta{
+		Attempts: attempts,
+		Duration: time.Since(start),
+	}
+	if resp == nil {
+		return
+	}
+
+	meta.StatusCode = resp.StatusCode
+	meta.Header = resp.Header
+	meta.Latency = resp.Latency
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			meta.RequestID = id
+			break
+		}
+	}
+	meta.RateLimit = parseRateLimit(resp.Header)
+	meta.Pagination = Pagination{
+		Total:      headerInt(resp.Header, "X-Total", "X-Total-Count"),
+		TotalPages: headerInt(resp.Header, "X-Total-Pages"),
+		Page:       headerInt(resp.Header, "X-Page"),
+		PerPage:    headerInt(resp.Header, "X-Per-Page"),
+	}
+}
+
+//parseRateLimit reads the usual X-RateLimit-* headers. Reset may be given as a unix timestamp or as seconds from now.
+func parseRateLimit(header http.Header) RateLimit {
+	rl := RateLimit{
+		Limit:     headerInt(header, "X-RateLimit-Limit", "RateLimit-Limit"),
+		Remaining: headerInt(header, "X-RateLimit-Remaining", "RateLimit-Remaining"),
+	}
+	if reset := headerInt(header, "X-RateLimit-Reset", "RateLimit-Reset"); reset > 0 {
+		//Values this big can't be a wait in seconds, so they're timestamps.
+		if reset > 1000000000 {
+			rl.Reset = time.Unix(int64(reset), 0)
+		} else {
+			rl.Reset = time.Now().Add(time.Duration(reset) * time.Second)
+		}
+	}
+	return rl
+}
+
+//headerInt returns the first of the given headers holding an int, or 0.
+func headerInt(header http.Header, names ...string) int {
+	for _, name := range names {
+		if n, err := strconv.Atoi(header.Get(name)); err == nil {
+			return n
+		}
+	}
+	return 0
+}
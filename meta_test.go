@@ -0,0 +1,62 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResponseMeta(t *testing.T) {
+	Convey("Given a local server sending request id, rate limit and pagination headers", t, func() {
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req_123")
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "42")
+			w.Header().Set("X-RateLimit-Reset", "30")
+			w.Header().Set("X-Total", "3")
+			w.Header().Set("X-Page", "1")
+			w.Header().Set("X-Per-Page", "2")
+			if r.URL.Path == "/events/missing" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Event not found"}}`))
+				return
+			}
+			w.Write([]byte(`[{"id": "evt_1", "type": "customer.created"}, {"id": "evt_2", "type": "customer.updated"}]`))
+		}))
+		defer srv.Close()
+
+		c := NewClient("token", true, WithBaseURL(srv.URL))
+
+		Convey("Listing with a meta in the context should fill it", func() {
+			var meta ResponseMeta
+			events, err := ListEventsWithContext(WithResponseMeta(context.Background(), &meta), c, 1, 2, nil, "")
+			So(err, ShouldBeNil)
+			So(events, ShouldHaveLength, 2)
+
+			So(meta.StatusCode, ShouldEqual, http.StatusOK)
+			So(meta.RequestID, ShouldEqual, "req_123")
+			So(meta.Attempts, ShouldEqual, 1)
+			So(meta.Latency, ShouldBeGreaterThan, 0)
+			So(meta.Duration, ShouldBeGreaterThanOrEqualTo, meta.Latency)
+			So(meta.RateLimit.Limit, ShouldEqual, 100)
+			So(meta.RateLimit.Remaining, ShouldEqual, 42)
+			So(meta.RateLimit.Reset, ShouldHappenWithin, 31*time.Second, time.Now())
+			So(meta.Pagination, ShouldResemble, Pagination{Total: 3, Page: 1, PerPage: 2})
+			So(meta.Header.Get("X-Total"), ShouldEqual, "3")
+		})
+
+		Convey("Failed calls should fill it too", func() {
+			var meta ResponseMeta
+			_, err := GetEventWithContext(WithResponseMeta(context.Background(), &meta), c, "missing")
+			So(err, ShouldNotBeNil)
+			So(meta.StatusCode, ShouldEqual, http.StatusNotFound)
+			So(meta.RequestID, ShouldEqual, "req_123")
+		})
+
+	})
+}
@@ -0,0 +1,216 @@
+package qvo
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//ErrNoResponse is returned when a middleware returns neither a response nor an error.
+var ErrNoResponse = errors.New("qvo: middleware returned no response")
+
+//Call is a single attempt of a request to the API, as seen by middleware. Middleware may modify it before passing it on.
+type Call struct {
+	Method   string
+	Endpoint string      //Path relative to the base url, e.g., "customers/cus_1".
+	Values   url.Values  //Form values for POST, PUT and PATCH, query values otherwise.
+	Header   http.Header //Extra headers sent with the request, such as the idempotency key.
+	Attempt  int         //Starts at 1 and grows on retries.
+}
+
+//Response is what the API answered to a Call. Non 2xx responses are Responses too; they're turned into an *APIError after the whole chain ran.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Latency    time.Duration
+	Raw        *http.Response //The raw response, whose body was already read into Body. It's nil for responses made up by middleware.
+}
+
+//Handler sends a Call and returns the API's Response, or an error if no response was received. Returning neither fails the call with ErrNoResponse.
+type Handler func(ctx context.Context, call *Call) (*Response, error)
+
+//Middleware wraps a Handler to act on every call made by the client: metrics, tracing, header injection, audit, fault injection, etc.
+//It may modify the call or the response, or short-circuit the call by not calling next.
+type Middleware func(next Handler) Handler
+
+//WithMiddleware appends middleware to the client's chain. The first one given is the outermost.
+//They run inside the default logging and dumping middleware, so those see what the added middleware did.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+//WithoutDefaultMiddleware removes the default logging and dumping middleware from the chain.
+func WithoutDefaultMiddleware() Option {
+	return func(c *Client) {
+		c.noDefaultMiddleware = true
+	}
+}
+
+//chain builds the client's handler: the default middleware, then the added one, around the transport.
+func (c *Client) chain() Handler {
+	mw := c.middleware
+	if !c.noDefaultMiddleware {
+		mw = append([]Middleware{c.loggingMiddleware, c.dumpMiddleware}, mw...)
+	}
+	h := Handler(c.transportHandler)
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+//getHandler returns the client's handler, building it for clients not created with NewClient.
+func (c *Client) getHandler() Handler {
+	if c.handler != nil {
+		return c.handler
+	}
+	return c.chain()
+}
+
+//newHTTPRequest builds the http request for a call.
+func (c *Client) newHTTPRequest(ctx context.Context, call *Call) (*http.Request, error) {
+	uri := c.getURI() + "/" + call.Endpoint
+
+	var req *http.Request
+	var err error
+	if call.Method == "POST" || call.Method == "PUT" || call.Method == "PATCH" {
+		body := call.Values.Encode()
+		req, err = http.NewRequestWithContext(ctx, call.Method, uri, bytes.NewBufferString(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req, err = http.NewRequestWithContext(ctx, call.Method, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.URL.RawQuery = call.Values.Encode()
+	}
+
+	for k, v := range call.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("authorization", c.getBearer())
+	if c.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	return req, nil
+}
+
+//transportHandler is the innermost handler: it sends the call through the client's http client and reads the whole response.
+func (c *Client) transportHandler(ctx context.Context, call *Call) (*Response, error) {
+	req, err := c.newHTTPRequest(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	response := &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Latency:    time.Since(start),
+		Raw:        resp,
+	}
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+//loggingMiddleware logs every call with its method, endpoint, attempt, status and duration.
+//Successful calls are logged at debug level, non 2xx responses at warn level and failed calls at error level.
+func (c *Client) loggingMiddleware(next Handler) Handler {
+	return func(ctx context.Context, call *Call) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, call)
+		if resp == nil && err == nil {
+			err = ErrNoResponse
+		}
+
+		fields := Fields{"method": call.Method, "endpoint": call.Endpoint, "attempt": call.Attempt, "duration": time.Since(start)}
+		if resp != nil {
+			fields["status"] = resp.StatusCode
+		}
+
+		switch {
+		case err != nil:
+			fields["error"] = err
+			c.log(LevelError, "qvo request failed", fields)
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			fields["error"] = newAPIError(resp.StatusCode, resp.Body).Message
+			c.log(LevelWarn, "qvo request returned an error", fields)
+		default:
+			c.log(LevelDebug, "qvo request", fields)
+		}
+
+		return resp, err
+	}
+}
+
+//dumpMiddleware dumps requests, and responses if enabled, masking secrets and PII. It does nothing unless debug logging is on.
+func (c *Client) dumpMiddleware(next Handler) Handler {
+	return func(ctx context.Context, call *Call) (*Response, error) {
+		if !c.getLogger().Enabled(LevelDebug) {
+			return next(ctx, call)
+		}
+
+		req, err := c.newHTTPRequest(ctx, call)
+		if err == nil {
+			var dr []byte
+			dr, err = httputil.DumpRequestOut(req, true)
+			if err == nil {
+				c.log(LevelDebug, "qvo request dump", Fields{"dump": c.redact(dr)})
+			}
+		}
+		if err != nil {
+			c.log(LevelError, "qvo request dump failed", Fields{"method": call.Method, "endpoint": call.Endpoint, "error": err})
+		}
+
+		resp, err := next(ctx, call)
+		if resp == nil || !c.dumpResponses {
+			return resp, err
+		}
+
+		raw := &http.Response{
+			Status:     http.StatusText(resp.StatusCode),
+			StatusCode: resp.StatusCode,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     resp.Header,
+		}
+		if resp.Raw != nil {
+			raw.Status, raw.Proto, raw.ProtoMajor, raw.ProtoMinor = resp.Raw.Status, resp.Raw.Proto, resp.Raw.ProtoMajor, resp.Raw.ProtoMinor
+		}
+		raw.Body = ioutil.NopCloser(bytes.NewReader(resp.Body))
+		raw.ContentLength = int64(len(resp.Body))
+		dresp, dumpErr := httputil.DumpResponse(raw, true)
+		if dumpErr != nil {
+			c.log(LevelError, "qvo response dump failed", Fields{"method": call.Method, "endpoint": call.Endpoint, "error": dumpErr})
+			return resp, err
+		}
+		c.log(LevelDebug, "qvo response dump", Fields{"dump": c.redact(dresp)})
+
+		return resp, err
+	}
+}
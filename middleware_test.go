@@ -0,0 +1,114 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMiddleware(t *testing.T) {
+	Convey("Given a local server standing in for QVO", t, func() {
+
+		var hits int32
+		var gotTrace string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			gotTrace = r.Header.Get("X-Trace-Id")
+			w.Write([]byte(`{"id": "wdr_1", "amount": 5000, "status": "processing"}`))
+		}))
+		defer srv.Close()
+
+		Convey("Middleware should run in order and see calls and responses", func() {
+			var order []string
+			var seen *Call
+			var latency time.Duration
+			record := func(name string) Middleware {
+				return func(next Handler) Handler {
+					return func(ctx context.Context, call *Call) (*Response, error) {
+						order = append(order, name)
+						resp, err := next(ctx, call)
+						order = append(order, name)
+						return resp, err
+					}
+				}
+			}
+			inspect := func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) (*Response, error) {
+					call.Header.Set("X-Trace-Id", "trace-1")
+					seen = call
+					resp, err := next(ctx, call)
+					if resp != nil {
+						latency = resp.Latency
+					}
+					return resp, err
+				}
+			}
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithMiddleware(record("outer"), record("inner"), inspect))
+
+			withdrawal, err := CreateWithdrawal(c, 5000)
+			So(err, ShouldBeNil)
+			So(withdrawal.ID, ShouldEqual, "wdr_1")
+			So(order, ShouldResemble, []string{"outer", "inner", "inner", "outer"})
+			So(seen.Method, ShouldEqual, "POST")
+			So(seen.Endpoint, ShouldEqual, "withdrawals")
+			So(seen.Values.Get("amount"), ShouldEqual, "5000")
+			So(seen.Attempt, ShouldEqual, 1)
+			So(seen.Header.Get(IdempotencyKeyHeader), ShouldNotBeEmpty)
+			So(latency, ShouldBeGreaterThan, 0)
+			So(gotTrace, ShouldEqual, "trace-1")
+		})
+
+		Convey("Middleware should be able to short-circuit calls", func() {
+			faulty := func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) (*Response, error) {
+					if call.Attempt == 1 {
+						return &Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: []byte(`{"error": {"type": "api_error", "message": "injected"}}`)}, nil
+					}
+					return next(ctx, call)
+				}
+			}
+
+			Convey("And injected faults should be retried like real ones", func() {
+				policy := DefaultRetryPolicy()
+				policy.InitialBackoff = time.Millisecond
+				c := NewClient("token", true, WithBaseURL(srv.URL), WithMiddleware(faulty), WithRetryPolicy(policy))
+
+				_, err := GetWithdrawal(c, "wdr_1")
+				So(err, ShouldBeNil)
+				So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+			})
+
+			Convey("And become typed errors when not retried", func() {
+				c := NewClient("token", true, WithBaseURL(srv.URL), WithMiddleware(faulty))
+
+				_, err := GetWithdrawal(c, "wdr_1")
+				So(errors.Is(err, ErrServer), ShouldBeTrue)
+				So(atomic.LoadInt32(&hits), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Middleware returning no response should fail the call instead of panicking", func() {
+			empty := func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) (*Response, error) {
+					return nil, nil
+				}
+			}
+
+			for _, c := range []*Client{
+				NewClient("token", true, WithBaseURL(srv.URL), WithMiddleware(empty)),
+				NewClient("token", true, WithBaseURL(srv.URL), WithMiddleware(empty), WithoutDefaultMiddleware()),
+			} {
+				_, err := GetWithdrawal(c, "wdr_1")
+				So(errors.Is(err, ErrNoResponse), ShouldBeTrue)
+			}
+			So(atomic.LoadInt32(&hits), ShouldEqual, 0)
+		})
+
+	})
+}
@@ -0,0 +1,52 @@
+package qvo
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+//DefaultTimeout is the per request timeout used when none is given to NewClient.
+const DefaultTimeout = 15 * time.Second
+
+//DefaultUserAgent is the User-Agent header sent when none is given to NewClient.
+const DefaultUserAgent = "qvo-go-client"
+
+//Option configures a Client at NewClient.
+type Option func(*Client)
+
+//WithHTTPClient makes the client send its requests through hc instead of building its own http.Client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+//WithTransport sets the RoundTripper used by the client's http.Client (proxies, custom TLS, instrumented transports, etc.).
+//It's ignored if WithHTTPClient is given too, as that client's transport is used.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+//WithBaseURL points the client at the given url instead of QVO's sandbox or production one, e.g., a local test server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+//WithTimeout sets the timeout for every request made by the client. A timeout <= 0 disables it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+//WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
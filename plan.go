@@ -1,15 +1,12 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 //Plan struct to represent a qvo plan object.
@@ -28,9 +25,45 @@ type Plan struct {
 	UpdatedAt         time.Time      `json:"updated_at"`
 }
 
-//CreatePlan creates a plan at QVOs end. Returns a copy of the plan if successful, and an error if not.
-//Price is a string as it may be an int or a float string representation DEPENDING on the currency (int for CLP, float for UF).
+//PlanService handles plans. A Client exposes it as its Plans field, which may be replaced with a fake in tests.
+type PlanService interface {
+	Create(ctx context.Context, plan Plan) (Plan, error)
+	Get(ctx context.Context, id string) (Plan, error)
+	Update(ctx context.Context, planID, name string) (Plan, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Plan, error)
+	ListByQuery(ctx context.Context, q *Query) ([]Plan, error)
+}
+
+//planResource is the plans endpoint.
+var planResource = resource[Plan]{path: "plans", idKey: "plan_id"}
+
+//planService implements PlanService through a Client.
+type planService struct {
+	c *Client
+}
+
+//plans returns the client's PlanService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) plans() PlanService {
+	if c.Plans != nil {
+		return c.Plans
+	}
+	return &planService{c: c}
+}
+
+//CreatePlan calls CreatePlanWithContext with a background context.
 func CreatePlan(c *Client, plan Plan) (Plan, error) {
+	return CreatePlanWithContext(context.Background(), c, plan)
+}
+
+//CreatePlanWithContext calls Create on the client's PlanService.
+func CreatePlanWithContext(ctx context.Context, c *Client, plan Plan) (Plan, error) {
+	return c.plans().Create(ctx, plan)
+}
+
+//Create creates a plan at QVOs end. Returns a copy of the plan if successful, and an error if not.
+//Price is a string as it may be an int or a float string representation DEPENDING on the currency (int for CLP, float for UF).
+func (s *planService) Create(ctx context.Context, plan Plan) (Plan, error) {
 	//Validate required fields.
 	if plan.ID == "" || plan.Name == "" {
 		return Plan{}, errors.New("can't create a plan without id or name")
@@ -50,127 +83,88 @@ func CreatePlan(c *Client, plan Plan) (Plan, error) {
 	form.Add("trial_period_days", strconv.FormatInt(int64(plan.TrialPeriodDays), 10))
 	form.Add("default_cycle_count", strconv.FormatInt(int64(plan.DefaultCycleCount), 10))
 
-	body, err := c.request("POST", "plans", form)
-	if err != nil {
-		return Plan{}, err
-	}
-
-	err = json.Unmarshal(body, &plan)
-
-	if err != nil {
+	if err := s.c.call(ctx, "POST", "plans", form, &plan); err != nil {
 		return Plan{}, err
 	}
 
 	return plan, nil
 }
 
-//GetPlan retrieves a plan by id.
+//GetPlan calls GetPlanWithContext with a background context.
 func GetPlan(c *Client, id string) (Plan, error) {
+	return GetPlanWithContext(context.Background(), c, id)
+}
 
-	endpoint := fmt.Sprintf("plans/%s", id)
-
-	form := url.Values{}
-	form.Add("plan_id", id)
-
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Plan{}, err
-	}
-
-	var plan Plan
-	err = json.Unmarshal(body, &plan)
-
-	if err != nil {
-		return Plan{}, err
-	}
-
-	return plan, nil
+//GetPlanWithContext calls Get on the client's PlanService.
+func GetPlanWithContext(ctx context.Context, c *Client, id string) (Plan, error) {
+	return c.plans().Get(ctx, id)
+}
 
+//Get retrieves a plan by id.
+func (s *planService) Get(ctx context.Context, id string) (Plan, error) {
+	return planResource.get(ctx, s.c, id)
 }
 
-//UpdatePlan updates a plan given its id.
+//UpdatePlan calls UpdatePlanWithContext with a background context.
 func UpdatePlan(c *Client, planID, name string) (Plan, error) {
+	return UpdatePlanWithContext(context.Background(), c, planID, name)
+}
+
+//UpdatePlanWithContext calls Update on the client's PlanService.
+func UpdatePlanWithContext(ctx context.Context, c *Client, planID, name string) (Plan, error) {
+	return c.plans().Update(ctx, planID, name)
+}
 
-	endpoint := fmt.Sprintf("plans/%s", planID)
+//Update updates a plan given its id.
+func (s *planService) Update(ctx context.Context, planID, name string) (Plan, error) {
 
 	form := url.Values{}
-	form.Set("plan_id", planID)
 	form.Set("name", name)
 
-	body, err := c.request("PUT", endpoint, form)
-	if err != nil {
-		return Plan{}, err
-	}
-
-	var plan Plan
-
-	err = json.Unmarshal(body, &plan)
-
-	log.Println(plan)
-
-	if err != nil {
-		return Plan{}, err
-	}
-
-	return plan, nil
-
+	return planResource.update(ctx, s.c, planID, form)
 }
 
-//DeletePlan deletes a plan given its id.
+//DeletePlan calls DeletePlanWithContext with a background context.
 func DeletePlan(c *Client, id string) error {
+	return DeletePlanWithContext(context.Background(), c, id)
+}
 
-	endpoint := fmt.Sprintf("plans/%s", id)
-
-	form := url.Values{}
-	form.Add("plan_id", id)
-
-	_, err := c.request("DELETE", endpoint, form)
-	if err != nil {
-		return err
-	}
-
-	return nil
+//DeletePlanWithContext calls Delete on the client's PlanService.
+func DeletePlanWithContext(ctx context.Context, c *Client, id string) error {
+	return c.plans().Delete(ctx, id)
+}
 
+//Delete deletes a plan given its id.
+func (s *planService) Delete(ctx context.Context, id string) error {
+	return planResource.delete(ctx, s.c, id, nil)
 }
 
-//ListPlans retrieves a list of plans with given pages, filters and order.
+//ListPlans calls ListPlansWithContext with a background context.
 func ListPlans(c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Plan, error) {
+	return ListPlansWithContext(context.Background(), c, page, perPage, where, orderBy)
+}
 
-	var plans = make([]Plan, 0)
-
-	form := url.Values{}
-	if page > 0 && perPage > 0 {
-		form.Add("page", strconv.Itoa(page))
-		form.Add("per_page", strconv.Itoa(perPage))
-	}
-
-	if len(where) > 0 {
-		jBytes, err := json.Marshal(where)
-		if err != nil {
-			log.Errorf("errored at where: %s", err)
-			return plans, err
-		}
-		form.Add("where", string(jBytes))
-	}
-
-	if orderBy != "" {
-		form.Add("order_by", orderBy)
-	}
-
-	body, err := c.request("GET", "plans", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return plans, err
-	}
+//ListPlansWithContext calls List on the client's PlanService.
+func ListPlansWithContext(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Plan, error) {
+	return c.plans().List(ctx, page, perPage, where, orderBy)
+}
 
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&plans)
+//List retrieves a list of plans with given pages, filters and order.
+func (s *planService) List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Plan, error) {
+	return planResource.list(ctx, s.c, page, perPage, where, orderBy)
+}
 
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return plans, err
-	}
+//ListPlansByQuery calls ListPlansByQueryWithContext with a background context.
+func ListPlansByQuery(c *Client, q *Query) ([]Plan, error) {
+	return ListPlansByQueryWithContext(context.Background(), c, q)
+}
 
-	return plans, nil
+//ListPlansByQueryWithContext calls ListByQuery on the client's PlanService.
+func ListPlansByQueryWithContext(ctx context.Context, c *Client, q *Query) ([]Plan, error) {
+	return c.plans().ListByQuery(ctx, q)
+}
 
+//ListByQuery retrieves a list of plans matching a query, after checking its fields are plan fields.
+func (s *planService) ListByQuery(ctx context.Context, q *Query) ([]Plan, error) {
+	return planResource.listByQuery(ctx, s.c, q)
 }
@@ -0,0 +1,317 @@
+package qvo
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//Operator is a comparison operator for a where filter.
+type Operator string
+
+//Operators accepted by the API's where filters.
+const (
+	OpEq    Operator = "="
+	OpNotEq Operator = "!="
+	OpGt    Operator = ">"
+	OpGte   Operator = ">="
+	OpLt    Operator = "<"
+	OpLte   Operator = "<="
+	OpIn    Operator = "in"    //Value must be a list.
+	OpNotIn Operator = "nin"   //Value must be a list.
+	OpLike  Operator = "like"  //Value must be a string, with % and _ wildcards.
+	OpILike Operator = "ilike" //Same as OpLike, but case insensitive.
+)
+
+//Direction is the direction of an order.
+type Direction string
+
+//Order directions.
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+//timeFormat is how times are sent to the API.
+const timeFormat = "2006-01-02T15:04:05.999Z"
+
+//ErrInvalidQuery is matched with errors.Is by the errors returned for queries that can't be sent, e.g., when filtering by a field the resource doesn't have.
+var ErrInvalidQuery = errors.New("qvo: invalid query")
+
+//order is a field to order by.
+type order struct {
+	field     string
+	direction Direction
+}
+
+//Query is a fluent builder for the where, order_by and pagination parameters of list calls:
+//
+//	q := qvo.NewQuery().Like("name", "%Gómez%").CreatedAfter(since).OrderBy("created_at", qvo.Desc).Page(1, 20)
+//	customers, err := qvo.ListCustomersByQuery(c, q)
+//
+//Fields are checked against the listed resource before sending, as the API answers unknown fields with a 500.
+//Fields of nested objects are given with dots, e.g., "customer.id". A nil Query lists everything.
+type Query struct {
+	filters []Filter
+	orders  []order
+	page    int
+	perPage int
+}
+
+//NewQuery returns an empty query.
+func NewQuery() *Query {
+	return &Query{}
+}
+
+//Where adds a filter. Time values, or lists of them, are sent in UTC as the API expects.
+func (q *Query) Where(attribute string, op Operator, value interface{}) *Query {
+	q.filters = append(q.filters, Filter{Attribute: attribute, Operator: string(op), Value: value})
+	return q
+}
+
+//Eq filters by attribute = value.
+func (q *Query) Eq(attribute string, value interface{}) *Query {
+	return q.Where(attribute, OpEq, value)
+}
+
+//NotEq filters by attribute != value.
+func (q *Query) NotEq(attribute string, value interface{}) *Query {
+	return q.Where(attribute, OpNotEq, value)
+}
+
+//Gt filters by attribute > value.
+func (q *Query) Gt(attribute string, value interface{}) *Query {
+	return q.Where(attribute, OpGt, value)
+}
+
+//Gte filters by attribute >= value.
+func (q *Query) Gte(attribute string, value interface{}) *Query {
+	return q.Where(attribute, OpGte, value)
+}
+
+//Lt filters by attribute < value.
+func (q *Query) Lt(attribute string, value interface{}) *Query {
+	return q.Where(attribute, OpLt, value)
+}
+
+//Lte filters by attribute <= value.
+func (q *Query) Lte(attribute string, value interface{}) *Query {
+	return q.Where(attribute, OpLte, value)
+}
+
+//In filters by attribute being one of values.
+func (q *Query) In(attribute string, values ...interface{}) *Query {
+	return q.Where(attribute, OpIn, values)
+}
+
+//NotIn filters by attribute being none of values.
+func (q *Query) NotIn(attribute string, values ...interface{}) *Query {
+	return q.Where(attribute, OpNotIn, values)
+}
+
+//Like filters by attribute matching pattern, with % and _ wildcards.
+func (q *Query) Like(attribute, pattern string) *Query {
+	return q.Where(attribute, OpLike, pattern)
+}
+
+//ILike filters by attribute matching pattern regardless of case.
+func (q *Query) ILike(attribute, pattern string) *Query {
+	return q.Where(attribute, OpILike, pattern)
+}
+
+//CreatedAfter filters by created_at > t.
+func (q *Query) CreatedAfter(t time.Time) *Query {
+	return q.Where("created_at", OpGt, t)
+}
+
+//CreatedBefore filters by created_at < t.
+func (q *Query) CreatedBefore(t time.Time) *Query {
+	return q.Where("created_at", OpLt, t)
+}
+
+//CreatedBetween filters by from <= created_at < to.
+func (q *Query) CreatedBetween(from, to time.Time) *Query {
+	return q.Where("created_at", OpGte, from).Where("created_at", OpLt, to)
+}
+
+//OrderBy adds a field to order by. Fields are applied in the order they were added.
+func (q *Query) OrderBy(field string, direction Direction) *Query {
+	q.orders = append(q.orders, order{field: field, direction: direction})
+	return q
+}
+
+//Page sets the page to retrieve and its size. Both must be positive for the API to paginate.
+func (q *Query) Page(page, perPage int) *Query {
+	q.page, q.perPage = page, perPage
+	return q
+}
+
+//Filters returns the query's filters.
+func (q *Query) Filters() []Filter {
+	if q == nil {
+		return nil
+	}
+	return append([]Filter(nil), q.filters...)
+}
+
+//clone returns a copy of the query that can be changed without changing it, or an empty query if it's nil.
+func (q *Query) clone() *Query {
+	if q == nil {
+		return NewQuery()
+	}
+	return &Query{
+		filters: append([]Filter(nil), q.filters...),
+		orders:  append([]order(nil), q.orders...),
+		page:    q.page,
+		perPage: q.perPage,
+	}
+}
+
+//Params returns the query as the parameters taken by the List functions, without checking fields against any resource.
+func (q *Query) Params() (page, perPage int, where map[string]map[string]interface{}, orderBy string, err error) {
+	if q == nil {
+		return 0, 0, nil, "", nil
+	}
+
+	if len(q.filters) > 0 {
+		where = make(map[string]map[string]interface{})
+	}
+	for _, f := range q.filters {
+		value, err := filterValue(f)
+		if err != nil {
+			return 0, 0, nil, "", err
+		}
+		if where[f.Attribute] == nil {
+			where[f.Attribute] = make(map[string]interface{})
+		}
+		if _, ok := where[f.Attribute][f.Operator]; ok {
+			return 0, 0, nil, "", errors.Wrapf(ErrInvalidQuery, "%s %s is filtered twice", f.Attribute, f.Operator)
+		}
+		where[f.Attribute][f.Operator] = value
+	}
+
+	orders := make([]string, 0, len(q.orders))
+	for _, o := range q.orders {
+		if o.direction != Asc && o.direction != Desc {
+			return 0, 0, nil, "", errors.Wrapf(ErrInvalidQuery, "unknown direction %q for %s", o.direction, o.field)
+		}
+		orders = append(orders, o.field+" "+string(o.direction))
+	}
+
+	return q.page, q.perPage, where, strings.Join(orders, ", "), nil
+}
+
+//build checks the query's fields against the listed resource and returns its parameters.
+func (q *Query) build(resource interface{}) (page, perPage int, where map[string]map[string]interface{}, orderBy string, err error) {
+	if q != nil {
+		t := reflect.TypeOf(resource)
+		for _, f := range q.filters {
+			if !hasField(t, f.Attribute) {
+				return 0, 0, nil, "", errors.Wrapf(ErrInvalidQuery, "%s has no field %q to filter by", strings.ToLower(t.Name()), f.Attribute)
+			}
+		}
+		for _, o := range q.orders {
+			if !hasField(t, o.field) {
+				return 0, 0, nil, "", errors.Wrapf(ErrInvalidQuery, "%s has no field %q to order by", strings.ToLower(t.Name()), o.field)
+			}
+		}
+	}
+	return q.Params()
+}
+
+//filterValue checks a filter's operator and returns its value as sent to the API.
+func filterValue(f Filter) (interface{}, error) {
+	switch Operator(f.Operator) {
+	case OpEq, OpNotEq, OpGt, OpGte, OpLt, OpLte:
+		return jsonValue(f.Value), nil
+	case OpLike, OpILike:
+		if _, ok := f.Value.(string); !ok {
+			return nil, errors.Wrapf(ErrInvalidQuery, "%s %s needs a string", f.Attribute, f.Operator)
+		}
+		return f.Value, nil
+	case OpIn, OpNotIn:
+		v := reflect.ValueOf(f.Value)
+		if f.Value == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+			return nil, errors.Wrapf(ErrInvalidQuery, "%s %s needs a list", f.Attribute, f.Operator)
+		}
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = jsonValue(v.Index(i).Interface())
+		}
+		return values, nil
+	}
+	return nil, errors.Wrapf(ErrInvalidQuery, "unknown operator %q for %s", f.Operator, f.Attribute)
+}
+
+//jsonValue formats times as the API expects, leaving other values as they are.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UTC().Format(timeFormat)
+	case *time.Time:
+		if t != nil {
+			return t.UTC().Format(timeFormat)
+		}
+	}
+	return v
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+//hasField tells if a resource type has a comparable field at a dotted path of json names. Anything is accepted below free form hashes, such as an event's data.
+func hasField(t reflect.Type, path string) bool {
+	ft, ok := fieldType(t, path)
+	if !ok {
+		return false
+	}
+
+	//Whole objects can't be compared.
+	ft = elem(ft)
+	return ft.Kind() != reflect.Struct || ft == timeType
+}
+
+//fieldType returns the type of the field at a dotted path of json names, or an empty interface's type below free form hashes.
+func fieldType(t reflect.Type, path string) (reflect.Type, bool) {
+	for _, name := range strings.Split(path, ".") {
+		t = elem(t)
+		if t.Kind() == reflect.Map || t.Kind() == reflect.Interface {
+			return reflect.TypeOf((*interface{})(nil)).Elem(), true
+		}
+		if t.Kind() != reflect.Struct || t == timeType {
+			return nil, false
+		}
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			if jsonName(t.Field(i)) == name {
+				t, found = t.Field(i).Type, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+//jsonName returns the name a struct field is encoded with, or "-" if it isn't.
+func jsonName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return "-"
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+//elem dereferences pointers and slices down to the type of their elements.
+func elem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
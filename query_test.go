@@ -0,0 +1,109 @@
+package qvo
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuery(t *testing.T) {
+	Convey("Given a query", t, func() {
+		since := time.Date(2018, 3, 1, 12, 0, 0, 0, time.FixedZone("CLT", -3*3600))
+		q := NewQuery().Like("name", "%Gómez%").In("email", "a@manglar.cl", "b@manglar.cl").CreatedAfter(since).OrderBy("created_at", Desc).OrderBy("name", Asc).Page(2, 10)
+
+		Convey("It should produce the where JSON the API expects", func() {
+			page, perPage, where, orderBy, err := q.Params()
+			So(err, ShouldBeNil)
+			So(page, ShouldEqual, 2)
+			So(perPage, ShouldEqual, 10)
+			So(orderBy, ShouldEqual, "created_at DESC, name ASC")
+
+			got, err := json.Marshal(where)
+			So(err, ShouldBeNil)
+			want, _ := json.Marshal(map[string]map[string]interface{}{
+				"name":       {"like": "%Gómez%"},
+				"email":      {"in": []string{"a@manglar.cl", "b@manglar.cl"}},
+				"created_at": {">": "2018-03-01T15:00:00Z"},
+			})
+			So(string(got), ShouldEqual, string(want))
+		})
+
+		Convey("Fields should be checked against the resource", func() {
+			_, _, _, _, err := q.build(Customer{})
+			So(err, ShouldBeNil)
+
+			_, _, _, _, err = NewQuery().Eq("created", "2018-03-01").build(Customer{})
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+			_, _, _, _, err = NewQuery().OrderBy("amount", Asc).build(Plan{})
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+			_, _, _, _, err = NewQuery().Eq("default_payment_method", "card").build(Customer{})
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+
+			_, _, _, _, err = NewQuery().Eq("default_payment_method.id", "card").build(Customer{})
+			So(err, ShouldBeNil)
+			_, _, _, _, err = NewQuery().Eq("data.customer.id", "cus_1").build(Event{})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Bad operators and values should be rejected", func() {
+			_, _, _, _, err := NewQuery().Where("name", "~", "x").Params()
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+			_, _, _, _, err = NewQuery().Where("name", OpIn, "x").Params()
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+			_, _, _, _, err = NewQuery().Gt("amount", 1).Gt("amount", 2).Params()
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+			_, _, _, _, err = NewQuery().OrderBy("name", "down").Params()
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a fake server with some customers", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		//The API compares times to the millisecond, so tick by seconds.
+		var ticks int64
+		srv.Now = func() time.Time {
+			return time.Date(2018, 3, 1, 12, 0, int(atomic.AddInt64(&ticks, 1)), 0, time.UTC)
+		}
+		c := NewClient(srv.Token, true, WithBaseURL(srv.URL))
+		ctx := context.Background()
+
+		first, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+		So(err, ShouldBeNil)
+		_, err = c.Customers.Create(ctx, "Jere Díaz", "test2@manglar.cl")
+		So(err, ShouldBeNil)
+		_, err = c.Customers.Create(ctx, "Pedro Gómez", "test3@manglar.cl")
+		So(err, ShouldBeNil)
+
+		Convey("Listing by query should filter and order them", func() {
+			customers, err := ListCustomersByQuery(c, NewQuery().Like("name", "%Gómez%").OrderBy("name", Desc))
+			So(err, ShouldBeNil)
+			So(customers, ShouldHaveLength, 2)
+			So(customers[0].Name, ShouldEqual, "Pedro Gómez")
+
+			customers, err = c.Customers.ListByQuery(ctx, NewQuery().CreatedAfter(first.CreatedAt).OrderBy("created_at", Asc))
+			So(err, ShouldBeNil)
+			So(customers, ShouldHaveLength, 2)
+			So(customers[0].Name, ShouldEqual, "Jere Díaz")
+		})
+
+		Convey("A nil query should list everything", func() {
+			customers, err := ListCustomersByQuery(c, nil)
+			So(err, ShouldBeNil)
+			So(customers, ShouldHaveLength, 3)
+		})
+
+		Convey("An invalid query shouldn't be sent", func() {
+			before := srv.Count("GET", "customers")
+			_, err := ListCustomersByQuery(c, NewQuery().Eq("created", "2018-03-01"))
+			So(errors.Is(err, ErrInvalidQuery), ShouldBeTrue)
+			So(srv.Count("GET", "customers"), ShouldEqual, before)
+		})
+	})
+}
@@ -0,0 +1,327 @@
+package qvotest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//InscriptionTTL is how long a card inscription may be completed.
+const InscriptionTTL = 10 * time.Minute
+
+//renderCustomer returns a customer with its default payment method, cards, subscriptions and transactions.
+func (s *Server) renderCustomer(customer object) object {
+	id := customer["id"]
+	rendered := make(object, len(customer)+4)
+	for k, v := range customer {
+		rendered[k] = v
+	}
+	rendered["default_payment_method"] = nil
+	if card := s.cards.get(str(customer["_default_card"])); card != nil {
+		rendered["default_payment_method"] = card
+	}
+	rendered["cards"] = s.cards.filter(func(o object) bool { return o["_customer_id"] == id })
+	rendered["subscriptions"] = s.subscriptions.filter(func(o object) bool { return o["_customer_id"] == id })
+	rendered["transactions"] = s.transactions.filter(func(o object) bool { return o["_customer_id"] == id })
+	return rendered
+}
+
+//snapshot returns a customer as embedded in other objects, without its lists.
+func snapshot(customer object) object {
+	copied := make(object, len(customer))
+	for k, v := range customer {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (s *Server) createCustomer(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, email := r.Form.Get("name"), r.Form.Get("email")
+	if !strings.Contains(email, "@") {
+		writeInvalid(w, "email", "email is invalid")
+		return
+	}
+	if taken := s.customers.filter(func(o object) bool { return o["email"] == email }); len(taken) > 0 {
+		writeInvalid(w, "email", "email has already been taken")
+		return
+	}
+
+	now := s.now()
+	customer := object{
+		"id":         s.newID("cus"),
+		"name":       name,
+		"email":      email,
+		"created_at": now,
+		"updated_at": now,
+	}
+	s.customers.add(customer)
+	rendered := s.renderCustomer(customer)
+	s.emit("customer.created", rendered, nil)
+	writeObject(w, http.StatusCreated, rendered)
+}
+
+func (s *Server) listCustomers(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list(w, r, s.customers.objects, s.renderCustomer)
+}
+
+func (s *Server) getCustomer(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer := s.customers.get(params[0])
+	if customer == nil {
+		writeNotFound(w, "customer", params[0])
+		return
+	}
+	writeObject(w, http.StatusOK, s.renderCustomer(customer))
+}
+
+func (s *Server) updateCustomer(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer := s.customers.get(params[0])
+	if customer == nil {
+		writeNotFound(w, "customer", params[0])
+		return
+	}
+
+	previous := object{}
+	if email := r.Form.Get("email"); email != "" && email != customer["email"] {
+		if !strings.Contains(email, "@") {
+			writeInvalid(w, "email", "email is invalid")
+			return
+		}
+		if taken := s.customers.filter(func(o object) bool { return o["email"] == email }); len(taken) > 0 {
+			writeInvalid(w, "email", "email has already been taken")
+			return
+		}
+		previous["email"] = customer["email"]
+		customer["email"] = email
+	}
+	if name := r.Form.Get("name"); name != "" && name != customer["name"] {
+		previous["name"] = customer["name"]
+		customer["name"] = name
+	}
+	if cardID := r.Form.Get("default_payment_method_id"); cardID != "" && cardID != customer["_default_card"] {
+		card := s.cards.get(cardID)
+		if card == nil || card["_customer_id"] != customer["id"] {
+			writeInvalid(w, "default_payment_method_id", fmt.Sprintf("card %s not found", cardID))
+			return
+		}
+		previous["default_payment_method"] = s.cards.get(str(customer["_default_card"]))
+		customer["_default_card"] = cardID
+	}
+	customer["updated_at"] = s.now()
+
+	rendered := s.renderCustomer(customer)
+	s.emit("customer.updated", rendered, previous)
+	writeObject(w, http.StatusOK, rendered)
+}
+
+func (s *Server) deleteCustomer(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer := s.customers.get(params[0])
+	if customer == nil {
+		writeNotFound(w, "customer", params[0])
+		return
+	}
+	rendered := s.renderCustomer(customer)
+	s.customers.remove(params[0])
+	for _, card := range s.cards.filter(func(o object) bool { return o["_customer_id"] == params[0] }) {
+		s.cards.remove(str(card["id"]))
+	}
+	s.emit("customer.deleted", rendered, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) createInscription(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.customers.get(params[0]) == nil {
+		writeNotFound(w, "customer", params[0])
+		return
+	}
+	returnURL := r.Form.Get("return_url")
+	if _, err := url.ParseRequestURI(returnURL); err != nil {
+		writeInvalid(w, "return_url", "return_url is invalid")
+		return
+	}
+
+	now := s.now()
+	uid := s.newID("ins")
+	s.inscriptions.add(object{
+		"id":           uid,
+		"uid":          uid,
+		"status":       "waiting_for_response",
+		"card":         nil,
+		"error":        nil,
+		"created_at":   now,
+		"updated_at":   now,
+		"_customer_id": params[0],
+		"_return_url":  returnURL,
+		"_expires_at":  now.Add(InscriptionTTL),
+	})
+	writeJSON(w, http.StatusCreated, object{
+		"inscription_uid": uid,
+		"redirect_url":    s.URL + "/webpay_oneclick/inscriptions/" + uid,
+		"expiration_date": now.Add(InscriptionTTL),
+	})
+}
+
+func (s *Server) getInscription(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inscription := s.inscriptions.get(params[1])
+	if inscription == nil || inscription["_customer_id"] != params[0] {
+		writeNotFound(w, "inscription", params[1])
+		return
+	}
+	writeObject(w, http.StatusOK, inscription)
+}
+
+//followInscription completes an inscription when its redirect url is followed, and redirects to the return url.
+func (s *Server) followInscription(w http.ResponseWriter, r *http.Request, uid string) {
+	returnURL, err := s.completeInscription(uid)
+	if err != nil {
+		writeNotFound(w, "inscription", uid)
+		return
+	}
+	http.Redirect(w, r, returnURL, http.StatusFound)
+}
+
+//CompleteInscription completes a card inscription as if the customer had entered a card, adding the card to the customer.
+//It returns the new card's id.
+func (s *Server) CompleteInscription(uid string) (string, error) {
+	if _, err := s.completeInscription(uid); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return str(s.inscriptions.get(uid)["card"].(object)["id"]), nil
+}
+
+//completeInscription completes an inscription, returning its return url.
+func (s *Server) completeInscription(uid string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inscription := s.inscriptions.get(uid)
+	if inscription == nil {
+		return "", fmt.Errorf("qvotest: inscription %s not found", uid)
+	}
+	if inscription["status"] != "waiting_for_response" {
+		return str(inscription["_return_url"]), nil
+	}
+
+	card := s.addCard(str(inscription["_customer_id"]))
+	if card == nil {
+		return "", fmt.Errorf("qvotest: customer %s not found", inscription["_customer_id"])
+	}
+	inscription["status"] = "succeeded"
+	inscription["card"] = card
+	inscription["updated_at"] = s.now()
+	return str(inscription["_return_url"]), nil
+}
+
+//AddCard adds a test card to a customer, skipping the inscription flow, and returns its id.
+func (s *Server) AddCard(customerID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	card := s.addCard(customerID)
+	if card == nil {
+		return "", fmt.Errorf("qvotest: customer %s not found", customerID)
+	}
+	return str(card["id"]), nil
+}
+
+//addCard adds a test card to a customer, making it the default payment method if there was none.
+func (s *Server) addCard(customerID string) object {
+	customer := s.customers.get(customerID)
+	if customer == nil {
+		return nil
+	}
+	card := object{
+		"id":            s.newID("card"),
+		"last_4_digits": "6623",
+		"card_type":     "VISA",
+		"payment_type":  "CD",
+		"failure_count": int64(0),
+		"created_at":    s.now(),
+		"_customer_id":  customerID,
+	}
+	s.cards.add(card)
+	if str(customer["_default_card"]) == "" {
+		customer["_default_card"] = card["id"]
+	}
+	s.emit("customer.card.created", card, nil)
+	return card
+}
+
+//customerCard returns a customer's card, or sends a not found error.
+func (s *Server) customerCard(w http.ResponseWriter, customerID, cardID string) object {
+	if s.customers.get(customerID) == nil {
+		writeNotFound(w, "customer", customerID)
+		return nil
+	}
+	card := s.cards.get(cardID)
+	if card == nil || card["_customer_id"] != customerID {
+		writeNotFound(w, "card", cardID)
+		return nil
+	}
+	return card
+}
+
+func (s *Server) getCard(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if card := s.customerCard(w, params[0], params[1]); card != nil {
+		writeObject(w, http.StatusOK, card)
+	}
+}
+
+func (s *Server) listCards(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.customers.get(params[0]) == nil {
+		writeNotFound(w, "customer", params[0])
+		return
+	}
+	list(w, r, s.cards.filter(func(o object) bool { return o["_customer_id"] == params[0] }), same)
+}
+
+func (s *Server) deleteCard(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	card := s.customerCard(w, params[0], params[1])
+	if card == nil {
+		return
+	}
+	s.cards.remove(params[1])
+	if customer := s.customers.get(params[0]); customer["_default_card"] == params[1] {
+		delete(customer, "_default_card")
+	}
+	s.emit("customer.card.deleted", card, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//str returns v if it's a string, or "".
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,21 @@
+package qvotest
+
+import "net/http"
+
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list(w, r, s.events.objects, same)
+}
+
+func (s *Server) getEvent(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := s.events.get(params[0])
+	if event == nil {
+		writeNotFound(w, "event", params[0])
+		return
+	}
+	writeObject(w, http.StatusOK, event)
+}
@@ -0,0 +1,222 @@
+package qvotest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+//Fault scripts a failure for the requests matching its method and path. Faults are checked in the order they were added, and the first matching one is applied.
+type Fault struct {
+	Method string //Matches any method if empty.
+	Path   string //Pattern for path.Match, without the leading slash, e.g., "customers/*/cards/*/charge". Matches any path if empty.
+	Times  int    //How many requests the fault applies to. 0 applies it until ClearFaults is called.
+
+	Delay           time.Duration //Wait before going on with the request, or until the client gives up.
+	Status          int           //Answer with this status and an error envelope, without handling the request.
+	RetryAfter      time.Duration //Sent as Retry-After header along with Status.
+	Drop            bool          //Close the connection without handling the request.
+	DropAfterCommit bool          //Handle the request, then close the connection without answering.
+}
+
+//GatewayResponse is the payment gateway's answer set on a scripted charge.
+type GatewayResponse struct {
+	Status  string
+	Message string
+}
+
+//Request is a request received by the server, kept for assertions.
+type Request struct {
+	Method string
+	Path   string //Without the leading slash.
+	Header http.Header
+	Form   url.Values
+}
+
+//chargeScript scripts the outcome of a charge.
+type chargeScript struct {
+	cardID  string
+	reject  *GatewayResponse
+	timeout time.Duration
+}
+
+//idempotentResponse is a response recorded for an idempotency key. done is closed once it's recorded, or dropped after a server error.
+type idempotentResponse struct {
+	method, path string
+	done         chan struct{}
+	status       int
+	header       http.Header
+	body         []byte
+}
+
+//Inject adds a fault.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+//FailNext makes the next n requests matching method and path (any of them, if empty) fail with the given status.
+func (s *Server) FailNext(n int, method, pattern string, status int) {
+	s.Inject(Fault{Method: method, Path: pattern, Times: n, Status: status})
+}
+
+//ClearFaults removes every fault and charge script.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults, s.charges = nil, nil
+}
+
+//RejectNextCharge makes the next charge on the given card (any card, if empty) be rejected with the given gateway response.
+//The transaction is created with status rejected, the card's failure count grows and a transaction.payment_failed event is emitted.
+func (s *Server) RejectNextCharge(cardID string, response GatewayResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.charges = append(s.charges, chargeScript{cardID: cardID, reject: &response})
+}
+
+//HoldNextCharge makes the next charge on the given card (any card, if empty) stay waiting_for_response until timeout elapses on the server's clock.
+//Then it changes to response_timeout and a transaction.response_timeout event is emitted.
+func (s *Server) HoldNextCharge(cardID string, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.charges = append(s.charges, chargeScript{cardID: cardID, timeout: timeout})
+}
+
+//Requests returns every request received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+//Count returns how many requests matched method and path (any of them, if empty).
+func (s *Server) Count(method, pattern string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if matchRequest(method, pattern, r.Method, r.Path) {
+			n++
+		}
+	}
+	return n
+}
+
+//matchRequest tells if a request's method and path match the given ones, which match anything if empty.
+func matchRequest(method, pattern, reqMethod, reqPath string) bool {
+	if method != "" && method != reqMethod {
+		return false
+	}
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, reqPath)
+	return ok
+}
+
+//takeFault returns the first fault matching a request, counting it.
+func (s *Server) takeFault(method, reqPath string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if !matchRequest(f.Method, f.Path, method, reqPath) {
+			continue
+		}
+		applied := *f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return &applied
+	}
+	return nil
+}
+
+//takeCharge returns the script for the next charge on a card, if any.
+func (s *Server) takeCharge(cardID string) *chargeScript {
+	for i, script := range s.charges {
+		if script.cardID == "" || script.cardID == cardID {
+			s.charges = append(s.charges[:i:i], s.charges[i+1:]...)
+			return &script
+		}
+	}
+	return nil
+}
+
+//reject marks a transaction as rejected by the gateway.
+func (s *Server) reject(transaction, card object, response GatewayResponse) {
+	transaction["status"] = "rejected"
+	transaction["gateway_response"] = object{"status": response.Status, "message": response.Message}
+	transaction["updated_at"] = s.now()
+	card["failure_count"] = card["failure_count"].(int64) + 1
+}
+
+//expireHeld times out held transactions whose deadline passed.
+func (s *Server) expireHeld() {
+	now := s.clock()
+	for _, transaction := range s.transactions.objects {
+		deadline, ok := transaction["_timeout_at"].(time.Time)
+		if !ok || transaction["status"] != "waiting_for_response" || now.Before(deadline) {
+			continue
+		}
+		delete(transaction, "_timeout_at")
+		transaction["status"] = "response_timeout"
+		transaction["gateway_response"] = object{"status": "timeout", "message": "the gateway didn't respond in time"}
+		transaction["updated_at"] = s.now()
+		s.emit("transaction.response_timeout", transaction, object{"status": "waiting_for_response"})
+	}
+}
+
+//applyFault applies a fault to a request. It returns true if the request was answered or dropped.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request, f *Fault, handle http.HandlerFunc) bool {
+	if f.Delay > 0 {
+		t := time.NewTimer(f.Delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	switch {
+	case f.Drop:
+		drop(w)
+		return true
+	case f.Status != 0:
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+		}
+		errorType := APIError
+		if f.Status == http.StatusTooManyRequests {
+			errorType = RateLimitError
+		}
+		writeError(w, f.Status, errorType, fmt.Sprintf("scripted %d", f.Status), "")
+		return true
+	case f.DropAfterCommit:
+		handle(httptest.NewRecorder(), r)
+		drop(w)
+		return true
+	}
+	return false
+}
+
+//drop closes the connection without answering.
+func drop(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic("qvotest: can't drop the connection")
+	}
+	conn, _, err := hj.Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
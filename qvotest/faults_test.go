@@ -0,0 +1,159 @@
+package qvotest_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//clock is a settable clock for the fake server.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestFaults(t *testing.T) {
+	Convey("Given a fake server with a customer and a card", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		clk := &clock{now: time.Now()}
+		srv.Now = clk.Now
+
+		policy := qvo.DefaultRetryPolicy()
+		policy.InitialBackoff, policy.MaxBackoff = time.Millisecond, time.Millisecond
+		policy.RetryIdempotentPOST = true
+		c := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL), qvo.WithRetryPolicy(policy))
+		ctx := context.Background()
+
+		customer, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+		So(err, ShouldBeNil)
+		cardID, err := srv.AddCard(customer.ID)
+		So(err, ShouldBeNil)
+
+		Convey("The next charge may be rejected with a given gateway response", func() {
+			srv.RejectNextCharge(cardID, qvotest.GatewayResponse{Status: "rejected", Message: "insufficient funds"})
+
+			transaction, err := c.Cards.Charge(ctx, customer.ID, cardID, "monthly fee", 19000)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "rejected")
+			So(transaction.GatewayResponse.Message, ShouldEqual, "insufficient funds")
+
+			card, err := c.Cards.Get(ctx, customer.ID, cardID)
+			So(err, ShouldBeNil)
+			So(card.FailureCount, ShouldEqual, 1)
+
+			transaction, err = c.Cards.Charge(ctx, customer.ID, cardID, "monthly fee", 19000)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "successful")
+		})
+
+		Convey("A held charge should time out on the server's clock", func() {
+			srv.HoldNextCharge("", time.Minute)
+
+			transaction, err := c.Cards.Charge(ctx, customer.ID, cardID, "monthly fee", 19000)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "waiting_for_response")
+
+			clk.Advance(2 * time.Minute)
+			transaction, err = c.Transactions.Get(ctx, transaction.ID)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "response_timeout")
+
+			events, err := c.Events.List(ctx, 0, 0, map[string]map[string]interface{}{"type": {"=": "transaction.response_timeout"}}, "")
+			So(err, ShouldBeNil)
+			So(events, ShouldHaveLength, 1)
+		})
+
+		Convey("Server errors should be retried", func() {
+			srv.FailNext(2, "GET", "customers/*", http.StatusInternalServerError)
+
+			_, err := c.Customers.Get(ctx, customer.ID)
+			So(err, ShouldBeNil)
+			So(srv.Count("GET", "customers/*"), ShouldEqual, 3)
+		})
+
+		Convey("A 429 should carry its Retry-After", func() {
+			srv.Inject(qvotest.Fault{Path: "customers", Times: 1, Status: http.StatusTooManyRequests, RetryAfter: time.Hour})
+
+			_, err := c.Customers.List(ctx, 0, 0, nil, "")
+			So(errors.Is(err, qvo.ErrRateLimited), ShouldBeTrue)
+		})
+
+		Convey("Slow responses should hit the client's timeout", func() {
+			srv.Inject(qvotest.Fault{Delay: time.Second})
+			slow := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL), qvo.WithTimeout(20*time.Millisecond))
+
+			_, err := slow.Customers.Get(ctx, customer.ID)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+
+		Convey("A charge retried after the connection dropped should be replayed instead of charged twice", func() {
+			srv.Inject(qvotest.Fault{Path: "customers/*/cards/*/charge", Times: 1, DropAfterCommit: true})
+
+			transaction, err := c.Cards.Charge(ctx, customer.ID, cardID, "monthly fee", 19000)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "successful")
+			So(srv.Count("POST", "customers/*/cards/*/charge"), ShouldEqual, 2)
+
+			requests := srv.Requests()
+			So(requests[len(requests)-1].Header.Get("Idempotency-Key"), ShouldEqual, requests[len(requests)-2].Header.Get("Idempotency-Key"))
+
+			transactions, err := c.Transactions.List(ctx, 0, 0, nil, "")
+			So(err, ShouldBeNil)
+			So(transactions, ShouldHaveLength, 1)
+		})
+
+		Convey("Concurrent charges with the same idempotency key should be charged once", func() {
+			keyed := qvo.WithIdempotencyKey(ctx, "charge-1")
+			ids := make([]string, 10)
+			var wg sync.WaitGroup
+			for i := range ids {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					//Separate clients, so the client's own dedup of in-flight keys doesn't hide the server's.
+					other := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL))
+					transaction, err := other.Cards.Charge(keyed, customer.ID, cardID, "monthly fee", 19000)
+					if err == nil {
+						ids[i] = transaction.ID
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			for _, id := range ids {
+				So(id, ShouldEqual, ids[0])
+			}
+			transactions, err := c.Transactions.List(ctx, 0, 0, nil, "")
+			So(err, ShouldBeNil)
+			So(transactions, ShouldHaveLength, 1)
+		})
+
+		Convey("ClearFaults should remove every fault", func() {
+			srv.Inject(qvotest.Fault{Status: http.StatusBadGateway})
+			srv.ClearFaults()
+
+			_, err := c.Customers.Get(ctx, customer.ID)
+			So(err, ShouldBeNil)
+		})
+	})
+}
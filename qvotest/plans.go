@@ -0,0 +1,167 @@
+package qvotest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//renderPlan returns a plan with its subscriptions.
+func (s *Server) renderPlan(plan object) object {
+	id := plan["id"]
+	rendered := make(object, len(plan)+1)
+	for k, v := range plan {
+		rendered[k] = v
+	}
+	rendered["subscriptions"] = s.subscriptions.filter(func(o object) bool { return o["_plan_id"] == id })
+	return rendered
+}
+
+//formInt parses an int form value, returning def if it's missing.
+func formInt(r *http.Request, key string, def int64) (int64, error) {
+	v := r.Form.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+	return n, nil
+}
+
+//addInterval adds count plan intervals to t.
+func addInterval(t time.Time, interval string, count int64) time.Time {
+	n := int(count)
+	switch interval {
+	case "day":
+		return t.AddDate(0, 0, n)
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "year":
+		return t.AddDate(n, 0, 0)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+func (s *Server) createPlan(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, name, price := r.Form.Get("id"), r.Form.Get("name"), r.Form.Get("price")
+	switch {
+	case id == "":
+		writeInvalid(w, "id", "id is required")
+		return
+	case s.plans.get(id) != nil:
+		writeInvalid(w, "id", "id has already been taken")
+		return
+	case name == "":
+		writeInvalid(w, "name", "name is required")
+		return
+	}
+	if p, err := strconv.ParseFloat(price, 64); err != nil || p < 0 {
+		writeInvalid(w, "price", "price must be a positive number")
+		return
+	}
+	currency := r.Form.Get("currency")
+	if currency != "CLP" && currency != "UF" {
+		writeInvalid(w, "currency", "currency must be CLP or UF")
+		return
+	}
+	interval := r.Form.Get("interval")
+	if interval == "" {
+		interval = "month"
+	}
+	if interval != "day" && interval != "week" && interval != "month" && interval != "year" {
+		writeInvalid(w, "interval", "interval must be one of day, week, month or year")
+		return
+	}
+
+	plan := object{
+		"id":       id,
+		"name":     name,
+		"price":    price,
+		"currency": currency,
+		"interval": interval,
+		"status":   "active",
+	}
+	for _, field := range []struct {
+		key string
+		def int64
+		min int64
+	}{{"interval_count", 1, 1}, {"trial_period_days", 0, 0}, {"default_cycle_count", 0, 0}} {
+		n, err := formInt(r, field.key, field.def)
+		if err == nil && n < field.min {
+			err = fmt.Errorf("%s must be at least %d", field.key, field.min)
+		}
+		if err != nil {
+			writeInvalid(w, field.key, err.Error())
+			return
+		}
+		plan[field.key] = n
+	}
+	now := s.now()
+	plan["created_at"], plan["updated_at"] = now, now
+
+	s.plans.add(plan)
+	rendered := s.renderPlan(plan)
+	s.emit("plan.created", rendered, nil)
+	writeObject(w, http.StatusCreated, rendered)
+}
+
+func (s *Server) listPlans(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list(w, r, s.plans.objects, s.renderPlan)
+}
+
+func (s *Server) getPlan(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := s.plans.get(params[0])
+	if plan == nil {
+		writeNotFound(w, "plan", params[0])
+		return
+	}
+	writeObject(w, http.StatusOK, s.renderPlan(plan))
+}
+
+func (s *Server) updatePlan(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := s.plans.get(params[0])
+	if plan == nil {
+		writeNotFound(w, "plan", params[0])
+		return
+	}
+
+	previous := object{}
+	if name := r.Form.Get("name"); name != "" && name != plan["name"] {
+		previous["name"] = plan["name"]
+		plan["name"] = name
+	}
+	plan["updated_at"] = s.now()
+
+	rendered := s.renderPlan(plan)
+	s.emit("plan.updated", rendered, previous)
+	writeObject(w, http.StatusOK, rendered)
+}
+
+func (s *Server) deletePlan(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := s.plans.get(params[0])
+	if plan == nil {
+		writeNotFound(w, "plan", params[0])
+		return
+	}
+	rendered := s.renderPlan(plan)
+	s.plans.remove(params[0])
+	s.emit("plan.deleted", rendered, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,335 @@
+package qvotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//collection holds objects in creation order.
+type collection struct {
+	objects []object
+}
+
+func (c *collection) add(o object) {
+	c.objects = append(c.objects, o)
+}
+
+func (c *collection) get(id string) object {
+	for _, o := range c.objects {
+		if o["id"] == id {
+			return o
+		}
+	}
+	return nil
+}
+
+func (c *collection) remove(id string) object {
+	for i, o := range c.objects {
+		if o["id"] == id {
+			c.objects = append(c.objects[:i:i], c.objects[i+1:]...)
+			return o
+		}
+	}
+	return nil
+}
+
+//filter returns the objects for which keep is true.
+func (c *collection) filter(keep func(object) bool) []object {
+	objects := []object{}
+	for _, o := range c.objects {
+		if keep(o) {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+//list answers a list request over objects, applying the where, order_by, page and per_page params as QVO does.
+//Pagination info is sent in the X-Total, X-Total-Pages, X-Page and X-Per-Page headers.
+func list(w http.ResponseWriter, r *http.Request, objects []object, render func(object) object) {
+	var where map[string]map[string]interface{}
+	if raw := r.Form.Get("where"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &where); err != nil {
+			writeInvalid(w, "where", fmt.Sprintf("invalid where: %s", err))
+			return
+		}
+	}
+
+	rendered := make([]object, 0, len(objects))
+	for _, o := range objects {
+		o = render(o)
+		ok, err := matches(o, where)
+		if err != nil {
+			writeInvalid(w, "where", err.Error())
+			return
+		}
+		if ok {
+			rendered = append(rendered, o)
+		}
+	}
+
+	if orderBy := r.Form.Get("order_by"); orderBy != "" {
+		if err := order(rendered, orderBy); err != nil {
+			writeInvalid(w, "order_by", err.Error())
+			return
+		}
+	}
+
+	total := len(rendered)
+	page, _ := strconv.Atoi(r.Form.Get("page"))
+	perPage, _ := strconv.Atoi(r.Form.Get("per_page"))
+	if page > 0 && perPage > 0 {
+		start := (page - 1) * perPage
+		if start > total {
+			start = total
+		}
+		end := start + perPage
+		if end > total {
+			end = total
+		}
+		rendered = rendered[start:end]
+
+		w.Header().Set("X-Total", strconv.Itoa(total))
+		w.Header().Set("X-Total-Pages", strconv.Itoa((total+perPage-1)/perPage))
+		w.Header().Set("X-Page", strconv.Itoa(page))
+		w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+	}
+
+	out := make([]object, len(rendered))
+	for i, o := range rendered {
+		out[i] = public(o)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+//lookup returns the value at a dotted path, such as "customer.id".
+func lookup(o object, path string) (interface{}, bool) {
+	var v interface{} = o
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(object)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+//matches tells if o satisfies every condition in where.
+//Supported operators are =, !=, <, <=, >, >=, like and ilike (with % and _ wildcards), in and nin (with a list).
+func matches(o object, where map[string]map[string]interface{}) (bool, error) {
+	for field, conditions := range where {
+		v, _ := lookup(o, field)
+		for op, want := range conditions {
+			ok, err := compare(v, op, want)
+			if err != nil {
+				return false, fmt.Errorf("%s: %s", field, err)
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+//compare applies an operator to an object's value and the value in the query.
+func compare(v interface{}, op string, want interface{}) (bool, error) {
+	switch op {
+	case "like", "ilike":
+		pattern, ok := want.(string)
+		if !ok {
+			return false, fmt.Errorf("%s needs a string", op)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return false, nil
+		}
+		return likeRegexp(pattern, op == "ilike").MatchString(s), nil
+	case "in", "nin":
+		values, ok := want.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s needs a list", op)
+		}
+		found := false
+		for _, value := range values {
+			if c, ok := cmp(v, value); ok && c == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (op == "in"), nil
+	}
+
+	c, ok := cmp(v, want)
+	switch op {
+	case "=", "==":
+		return ok && c == 0, nil
+	case "!=", "<>":
+		return !ok || c != 0, nil
+	case "<":
+		return ok && c < 0, nil
+	case "<=":
+		return ok && c <= 0, nil
+	case ">":
+		return ok && c > 0, nil
+	case ">=":
+		return ok && c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+//cmp compares an object's value with a value decoded from JSON, returning false if they can't be compared.
+//Times are compared with RFC 3339 strings, and numbers with numbers or numeric strings.
+func cmp(v, want interface{}) (int, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		s, ok := want.(string)
+		if !ok {
+			return 0, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case v.Before(t):
+			return -1, true
+		case v.After(t):
+			return 1, true
+		}
+		return 0, true
+	case string:
+		s, ok := want.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v, s), true
+	case bool:
+		b, ok := want.(bool)
+		if !ok || v == b {
+			return 0, ok
+		}
+		if v {
+			return 1, true
+		}
+		return -1, true
+	case nil:
+		if want == nil {
+			return 0, true
+		}
+		return 0, false
+	}
+
+	f, ok := number(v)
+	if !ok {
+		return 0, false
+	}
+	g, ok := number(want)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case f < g:
+		return -1, true
+	case f > g:
+		return 1, true
+	}
+	return 0, true
+}
+
+//number converts numeric values, and numeric strings, to float64.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+//likeRegexp translates a SQL like pattern to a regexp.
+func likeRegexp(pattern string, insensitive bool) *regexp.Regexp {
+	var b strings.Builder
+	if insensitive {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+//order sorts objects by an order_by param, such as "created_at DESC" or "amount DESC, created_at ASC". Ties keep creation order.
+func order(objects []object, orderBy string) error {
+	type key struct {
+		field string
+		desc  bool
+	}
+	var keys []key
+	for _, part := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 || len(fields) > 2 {
+			return fmt.Errorf("invalid order %q", part)
+		}
+		k := key{field: fields[0]}
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "ASC":
+			case "DESC":
+				k.desc = true
+			default:
+				return fmt.Errorf("invalid direction %q", fields[1])
+			}
+		}
+		keys = append(keys, k)
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		for _, k := range keys {
+			a, _ := lookup(objects[i], k.field)
+			b, _ := lookup(objects[j], k.field)
+			c, ok := cmpValues(a, b)
+			if !ok || c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+//cmpValues compares two values of objects.
+func cmpValues(a, b interface{}) (int, bool) {
+	if t, ok := b.(time.Time); ok {
+		b = t.Format(time.RFC3339Nano)
+	}
+	return cmp(a, b)
+}
@@ -0,0 +1,369 @@
+//Package qvotest provides an in-memory fake of the QVO API for offline tests.
+//
+//The fake implements customers, cards, card inscriptions, plans, subscriptions, transactions, refunds, events, withdrawals and webpay,
+//answering with QVO shaped JSON and error envelopes, and emitting an event for each mutation. Point a client at it with qvo.WithBaseURL:
+//
+//	srv := qvotest.NewServer()
+//	defer srv.Close()
+//	c := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL))
+//
+//Redirect urls for card inscriptions and webpay transactions point back at the fake: following them completes the flow successfully and redirects to the return url.
+//CompleteInscription and CompleteWebpay do the same without a browser.
+//
+//Failures may be scripted to test retries, idempotency and dunning: RejectNextCharge and HoldNextCharge script the outcome of charges,
+//and Inject adds faults such as 5xx or 429 responses, slow responses and connections dropped before or after the request is handled.
+//Mutating requests with an Idempotency-Key header are replayed like the API does, so a retried charge isn't charged twice.
+//
+//The package doesn't import the client, so the client's own tests may use it.
+package qvotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+//DefaultToken is the token a new Server expects.
+const DefaultToken = "qvotest-token"
+
+//Error types sent in error envelopes.
+const (
+	InvalidRequestError = "invalid_request_error"
+	AuthenticationError = "authentication_error"
+	NotFoundError       = "not_found_error"
+	RateLimitError      = "rate_limit_error"
+	APIError            = "api_error"
+)
+
+//object is a QVO object as sent in JSON. Keys starting with "_" are kept by the fake and never sent.
+type object map[string]interface{}
+
+//Server is a stateful fake of the QVO API running on a local httptest.Server. It's safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	Token string           //Requests must carry it as bearer token. Empty accepts any token.
+	Now   func() time.Time //Clock used for timestamps. It defaults to time.Now.
+
+	mu            sync.Mutex
+	seq           int
+	last          time.Time
+	customers     collection
+	cards         collection
+	inscriptions  collection
+	plans         collection
+	subscriptions collection
+	transactions  collection
+	events        collection
+	withdrawals   collection
+
+	faults     []*Fault
+	charges    []chargeScript
+	requests   []Request
+	idempotent map[string]*idempotentResponse
+}
+
+//NewServer starts a fake QVO API expecting DefaultToken. The caller should Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Token: DefaultToken,
+		Now:   time.Now,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+//Reset drops every object, recorded request and idempotency key, leaving the server as new but for its faults.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq, s.last = 0, time.Time{}
+	s.customers, s.cards, s.inscriptions, s.plans = collection{}, collection{}, collection{}, collection{}
+	s.subscriptions, s.transactions, s.events, s.withdrawals = collection{}, collection{}, collection{}, collection{}
+	s.requests, s.idempotent = nil, nil
+}
+
+//route is a handler for a matched path. Its params are the path segments standing for ids.
+type route func(w http.ResponseWriter, r *http.Request, params []string)
+
+//serveHTTP records the request and applies the first matching fault before handling it.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	reqPath := strings.Trim(r.URL.Path, "/")
+	segments := strings.Split(reqPath, "/")
+
+	//Redirects are followed by the customer's browser, so they don't carry the token.
+	if r.Method == "GET" && len(segments) == 3 && segments[1] != "" {
+		switch segments[0] + "/" + segments[1] {
+		case "webpay_oneclick/inscriptions":
+			s.followInscription(w, r, segments[2])
+			return
+		case "webpay_plus/transactions":
+			s.followWebpay(w, r, segments[2])
+			return
+		}
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, InvalidRequestError, err.Error(), "")
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: reqPath, Header: r.Header.Clone(), Form: r.Form})
+	s.mu.Unlock()
+
+	if f := s.takeFault(r.Method, reqPath); f != nil && s.applyFault(w, r, f, s.handle) {
+		return
+	}
+	s.handle(w, r)
+}
+
+//handle authenticates the request, replays the response for a known idempotency key, or routes it by method and path.
+//Responses to mutating requests with an Idempotency-Key header are recorded, unless they're a server error.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer: "+s.Token {
+		writeError(w, http.StatusUnauthorized, AuthenticationError, "invalid token", "")
+		return
+	}
+
+	reqPath := strings.Trim(r.URL.Path, "/")
+	h, params := s.match(r.Method, strings.Split(reqPath, "/"))
+	if h == nil {
+		writeError(w, http.StatusNotFound, NotFoundError, fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path), "")
+		return
+	}
+
+	s.mu.Lock()
+	s.expireHeld()
+	s.mu.Unlock()
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || (r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH") {
+		h(w, r, params)
+		return
+	}
+
+	//Reserve the key before handling the request, so concurrent requests with it wait for this one instead of running twice.
+	var recorded, reserved *idempotentResponse
+	for {
+		s.mu.Lock()
+		recorded = s.idempotent[key]
+		if recorded == nil {
+			if s.idempotent == nil {
+				s.idempotent = make(map[string]*idempotentResponse)
+			}
+			reserved = &idempotentResponse{method: r.Method, path: reqPath, done: make(chan struct{})}
+			s.idempotent[key] = reserved
+		}
+		s.mu.Unlock()
+		if recorded == nil || recorded.method != r.Method || recorded.path != reqPath {
+			break
+		}
+		select {
+		case <-recorded.done:
+		case <-r.Context().Done():
+			return
+		}
+		//A failed request drops its reservation, so try again.
+		s.mu.Lock()
+		dropped := s.idempotent[key] != recorded
+		s.mu.Unlock()
+		if !dropped {
+			break
+		}
+	}
+	if recorded != nil {
+		if recorded.method != r.Method || recorded.path != reqPath {
+			writeError(w, http.StatusUnprocessableEntity, InvalidRequestError, "idempotency key was used for another request", "Idempotency-Key")
+			return
+		}
+		for k, v := range recorded.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(recorded.status)
+		w.Write(recorded.body)
+		return
+	}
+
+	rec := httptest.NewRecorder()
+	h(rec, r, params)
+	s.mu.Lock()
+	reserved.status, reserved.header, reserved.body = rec.Code, rec.Header(), rec.Body.Bytes()
+	if rec.Code >= 500 && s.idempotent[key] == reserved {
+		delete(s.idempotent, key)
+	}
+	s.mu.Unlock()
+	close(reserved.done)
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+//match finds the route for a method and path. Segments starting with ":" in patterns match any id.
+func (s *Server) match(method string, segments []string) (route, []string) {
+	routes := []struct {
+		method  string
+		pattern string
+		handler route
+	}{
+		{"POST", "customers", s.createCustomer},
+		{"GET", "customers", s.listCustomers},
+		{"GET", "customers/:id", s.getCustomer},
+		{"PUT", "customers/:id", s.updateCustomer},
+		{"DELETE", "customers/:id", s.deleteCustomer},
+		{"POST", "customers/:id/cards/inscriptions", s.createInscription},
+		{"GET", "customers/:id/cards/inscriptions/:uid", s.getInscription},
+		{"GET", "customers/:id/cards", s.listCards},
+		{"GET", "customers/:id/cards/:card", s.getCard},
+		{"DELETE", "customers/:id/cards/:card", s.deleteCard},
+		{"POST", "customers/:id/cards/:card/charge", s.chargeCard},
+		{"POST", "plans", s.createPlan},
+		{"GET", "plans", s.listPlans},
+		{"GET", "plans/:id", s.getPlan},
+		{"PUT", "plans/:id", s.updatePlan},
+		{"DELETE", "plans/:id", s.deletePlan},
+		{"POST", "subscriptions", s.createSubscription},
+		{"GET", "subscriptions", s.listSubscriptions},
+		{"GET", "subscriptions/:id", s.getSubscription},
+		{"PUT", "subscriptions/:id", s.updateSubscription},
+		{"DELETE", "subscriptions/:id", s.cancelSubscription},
+		{"GET", "transactions", s.listTransactions},
+		{"GET", "transactions/:id", s.getTransaction},
+		{"POST", "transactions/:id/refund", s.refundTransaction},
+		{"POST", "webpay_plus/charge", s.createWebpay},
+		{"GET", "events", s.listEvents},
+		{"GET", "events/:id", s.getEvent},
+		{"POST", "withdrawals", s.createWithdrawal},
+		{"GET", "withdrawals", s.listWithdrawals},
+		{"GET", "withdrawals/:id", s.getWithdrawal},
+	}
+
+	for _, rt := range routes {
+		if rt.method != method {
+			continue
+		}
+		pattern := strings.Split(rt.pattern, "/")
+		if len(pattern) != len(segments) {
+			continue
+		}
+		var params []string
+		matched := true
+		for i, p := range pattern {
+			if strings.HasPrefix(p, ":") && segments[i] != "" {
+				params = append(params, segments[i])
+			} else if p != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rt.handler, params
+		}
+	}
+	return nil, nil
+}
+
+//newID returns a new id with the given prefix.
+func (s *Server) newID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_%06d", prefix, s.seq)
+}
+
+//clock returns the current time of the server's clock.
+func (s *Server) clock() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+//now returns the current time of the server's clock for timestamps. They never repeat, so objects are always ordered by creation.
+func (s *Server) now() time.Time {
+	now := s.clock()
+	if !now.After(s.last) {
+		now = s.last.Add(time.Nanosecond)
+	}
+	s.last = now
+	return now
+}
+
+//emit records an event for a mutation. previous holds the changed attributes' old values, if any.
+func (s *Server) emit(eventType string, data, previous object) {
+	event := object{
+		"id":         s.newID("evt"),
+		"type":       eventType,
+		"data":       public(data),
+		"previous":   nil,
+		"created_at": s.now(),
+	}
+	if len(previous) > 0 {
+		event["previous"] = public(previous)
+	}
+	s.events.add(event)
+}
+
+//public returns a copy of o without the fake's own keys, ready to be sent.
+func public(o object) object {
+	if o == nil {
+		return nil
+	}
+	p := make(object, len(o))
+	for k, v := range o {
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		switch v := v.(type) {
+		case object:
+			p[k] = public(v)
+		case []object:
+			list := make([]object, len(v))
+			for i, item := range v {
+				list[i] = public(item)
+			}
+			p[k] = list
+		default:
+			p[k] = v
+		}
+	}
+	return p
+}
+
+//writeJSON sends v with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+//writeObject sends o without the fake's own keys.
+func writeObject(w http.ResponseWriter, status int, o object) {
+	writeJSON(w, status, public(o))
+}
+
+//writeError sends a QVO error envelope.
+func writeError(w http.ResponseWriter, status int, errorType, message, param string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]string{
+			"type":    errorType,
+			"message": message,
+			"param":   param,
+		},
+	})
+}
+
+//writeNotFound sends a not found error for the given kind of object.
+func writeNotFound(w http.ResponseWriter, kind, id string) {
+	writeError(w, http.StatusNotFound, NotFoundError, fmt.Sprintf("%s %s not found", kind, id), "id")
+}
+
+//writeInvalid sends an invalid request error for the given param.
+func writeInvalid(w http.ResponseWriter, param, message string) {
+	writeError(w, http.StatusUnprocessableEntity, InvalidRequestError, message, param)
+}
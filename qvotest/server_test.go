@@ -0,0 +1,155 @@
+package qvotest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServer(t *testing.T) {
+	Convey("Given a fake server and a client pointed at it", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		c := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL))
+		ctx := context.Background()
+
+		Convey("A wrong token should be rejected", func() {
+			bad := qvo.NewClient("wrong", true, qvo.WithBaseURL(srv.URL))
+			_, err := qvo.ListCustomers(bad, 0, 0, nil, "")
+			So(errors.Is(err, qvo.ErrUnauthorized), ShouldBeTrue)
+		})
+
+		Convey("Unknown objects should be not found", func() {
+			_, err := qvo.GetCustomer(c, "cus_nope")
+			So(errors.Is(err, qvo.ErrNotFound), ShouldBeTrue)
+		})
+
+		Convey("A customer should be able to enroll a card, pay and get refunded", func() {
+			customer, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+			So(err, ShouldBeNil)
+
+			inscription, err := c.Cards.CreateInscription(ctx, customer.ID, "https://example.com/return")
+			So(err, ShouldBeNil)
+
+			//Following the redirect completes the inscription and lands on the return url.
+			noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			resp, err := noRedirect.Get(inscription.RedirectURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusFound)
+			So(resp.Header.Get("Location"), ShouldEqual, "https://example.com/return")
+			resp.Body.Close()
+
+			state, err := c.Cards.GetInscription(ctx, customer.ID, inscription.InscriptionUID)
+			So(err, ShouldBeNil)
+			So(state.Status, ShouldEqual, qvo.Succeeded)
+			So(state.Card, ShouldNotBeNil)
+
+			customer, err = c.Customers.Get(ctx, customer.ID)
+			So(err, ShouldBeNil)
+			So(customer.DefaultPaymentMethod.ID, ShouldEqual, state.Card.ID)
+			So(customer.Cards, ShouldHaveLength, 1)
+			cards, err := c.Cards.List(ctx, customer.ID)
+			So(err, ShouldBeNil)
+			So(cards, ShouldHaveLength, 1)
+			So(cards[0].ID, ShouldEqual, state.Card.ID)
+
+			transaction, err := c.Cards.Charge(ctx, customer.ID, state.Card.ID, "monthly fee", 19000)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "successful")
+			So(transaction.Payment.Amount, ShouldEqual, 19000)
+			So(transaction.Customer.ID, ShouldEqual, customer.ID)
+
+			refund, err := c.Transactions.Refund(ctx, transaction.ID)
+			So(err, ShouldBeNil)
+			So(refund.Amount, ShouldEqual, 19000)
+
+			_, err = c.Transactions.Refund(ctx, transaction.ID)
+			So(errors.Is(err, qvo.ErrInvalidRequest), ShouldBeTrue)
+
+			events, err := c.Events.List(ctx, 0, 0, nil, "created_at ASC")
+			So(err, ShouldBeNil)
+			var types []string
+			for _, event := range events {
+				types = append(types, event.Type)
+			}
+			So(types, ShouldResemble, []string{"customer.created", "customer.card.created", "transaction.payment_succeeded", "transaction.refunded"})
+			So(events[3].Data["status"], ShouldEqual, "refunded")
+			So((*events[3].Previous)["status"], ShouldEqual, "successful")
+		})
+
+		Convey("Webpay transactions should wait for the customer", func() {
+			customer, err := c.Customers.Create(ctx, "Jere Díaz", "test2@manglar.cl")
+			So(err, ShouldBeNil)
+
+			webpay, err := c.Webpay.Transaction(ctx, customer.ID, "https://example.com/return", "order 1", 5000)
+			So(err, ShouldBeNil)
+			transaction, err := c.Transactions.Get(ctx, webpay.TransactionID)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "waiting_for_response")
+			So(transaction.Description, ShouldEqual, "order 1")
+
+			So(srv.CompleteWebpay(webpay.TransactionID), ShouldBeNil)
+			transaction, err = c.Transactions.Get(ctx, webpay.TransactionID)
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, "successful")
+		})
+
+		Convey("Subscriptions should take the plan's defaults and accrue debt when upgraded", func() {
+			customer, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+			So(err, ShouldBeNil)
+			basic, err := c.Plans.Create(ctx, qvo.Plan{ID: "basic", Name: "Basic", Price: "19990", Currency: "CLP", Interval: "month", IntervalCount: 1, DefaultCycleCount: 3})
+			So(err, ShouldBeNil)
+			pro, err := c.Plans.Create(ctx, qvo.Plan{ID: "pro", Name: "Pro", Price: "29990", Currency: "CLP", Interval: "month", IntervalCount: 1})
+			So(err, ShouldBeNil)
+
+			subscription, err := c.Subscriptions.Create(ctx, customer.ID, basic.ID, "IVA", 19, 0, nil)
+			So(err, ShouldBeNil)
+			So(subscription.Status, ShouldEqual, "active")
+			So(subscription.CycleCount, ShouldEqual, 3)
+			So(subscription.TaxPercent, ShouldEqual, "19.0")
+
+			subscription, err = c.Subscriptions.Update(ctx, subscription.ID, pro.ID)
+			So(err, ShouldBeNil)
+			So(subscription.Debt, ShouldEqual, 10000)
+			So(subscription.Plan.ID, ShouldEqual, "pro")
+
+			where := map[string]map[string]interface{}{"debt": {">": 0}}
+			withDebt, err := c.Subscriptions.List(ctx, 0, 0, where, "")
+			So(err, ShouldBeNil)
+			So(withDebt, ShouldHaveLength, 1)
+
+			So(c.Subscriptions.Cancel(ctx, subscription.ID, false), ShouldBeNil)
+			subscription, err = c.Subscriptions.Get(ctx, subscription.ID)
+			So(err, ShouldBeNil)
+			So(subscription.Status, ShouldEqual, "canceled")
+		})
+
+		Convey("Lists should be paginated", func() {
+			for _, amount := range []int64{1000, 2000, 3000} {
+				_, err := c.Withdrawals.Create(ctx, amount)
+				So(err, ShouldBeNil)
+			}
+
+			var meta qvo.ResponseMeta
+			withdrawals, err := c.Withdrawals.List(qvo.WithResponseMeta(ctx, &meta), 2, 2, nil, "amount DESC")
+			So(err, ShouldBeNil)
+			So(withdrawals, ShouldHaveLength, 1)
+			So(withdrawals[0].Amount, ShouldEqual, 1000)
+			So(meta.Pagination, ShouldResemble, qvo.Pagination{Total: 3, TotalPages: 2, Page: 2, PerPage: 2})
+		})
+
+		Convey("Reset should drop everything", func() {
+			_, err := c.Customers.Create(ctx, "Ignacio Gómez", "test@manglar.cl")
+			So(err, ShouldBeNil)
+			srv.Reset()
+			customers, err := c.Customers.List(ctx, 0, 0, nil, "")
+			So(err, ShouldBeNil)
+			So(customers, ShouldBeEmpty)
+		})
+	})
+}
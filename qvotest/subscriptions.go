@@ -0,0 +1,193 @@
+package qvotest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//renderSubscription returns a subscription with its transactions.
+func (s *Server) renderSubscription(subscription object) object {
+	id := subscription["id"]
+	rendered := make(object, len(subscription)+1)
+	for k, v := range subscription {
+		rendered[k] = v
+	}
+	rendered["transactions"] = s.transactions.filter(func(o object) bool { return o["_subscription_id"] == id })
+	return rendered
+}
+
+//parseTime parses the timestamps sent by the client.
+func parseTime(v string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02T15:04:05.999Z", v)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339Nano, v)
+	}
+	return t, err
+}
+
+func (s *Server) createSubscription(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer := s.customers.get(r.Form.Get("customer_id"))
+	if customer == nil {
+		writeInvalid(w, "customer_id", "customer not found")
+		return
+	}
+	plan := s.plans.get(r.Form.Get("plan_id"))
+	if plan == nil || plan["status"] != "active" {
+		writeInvalid(w, "plan_id", "plan not found")
+		return
+	}
+
+	now := s.now()
+	start := now
+	if v := r.Form.Get("start"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			writeInvalid(w, "start", "start is invalid")
+			return
+		}
+		start = t
+	}
+	cycleCount, err := formInt(r, "cycle_count", plan["default_cycle_count"].(int64))
+	if err != nil || cycleCount < 0 {
+		writeInvalid(w, "cycle_count", "cycle_count must be a positive integer")
+		return
+	}
+	taxName, taxPercent := r.Form.Get("tax_name"), r.Form.Get("tax_percent")
+	if taxName != "" {
+		p, err := strconv.ParseFloat(taxPercent, 64)
+		if err != nil || p < 0 || p > 100 {
+			writeInvalid(w, "tax_percent", "tax_percent must be between 0 and 100")
+			return
+		}
+		taxPercent = strconv.FormatFloat(p, 'f', -1, 64)
+		if !strings.Contains(taxPercent, ".") {
+			taxPercent += ".0"
+		}
+	}
+
+	interval, intervalCount := plan["interval"].(string), plan["interval_count"].(int64)
+	status, periodEnd := "active", addInterval(start, interval, intervalCount)
+	if trial := plan["trial_period_days"].(int64); trial > 0 {
+		status, periodEnd = "trialing", start.AddDate(0, 0, int(trial))
+	}
+	var end interface{}
+	if cycleCount > 0 {
+		end = addInterval(start, interval, intervalCount*cycleCount)
+	}
+
+	subscription := object{
+		"id":                   s.newID("sub"),
+		"status":               status,
+		"debt":                 int64(0),
+		"start":                start,
+		"end":                  end,
+		"cycle_count":          cycleCount,
+		"current_period_start": start,
+		"current_period_end":   periodEnd,
+		"customer":             snapshot(customer),
+		"plan":                 snapshot(plan),
+		"tax_name":             taxName,
+		"tax_percent":          taxPercent,
+		"created_at":           now,
+		"updated_at":           now,
+		"_customer_id":         customer["id"],
+		"_plan_id":             plan["id"],
+	}
+	s.subscriptions.add(subscription)
+	rendered := s.renderSubscription(subscription)
+	s.emit("customer.subscription.created", rendered, nil)
+	writeObject(w, http.StatusCreated, rendered)
+}
+
+func (s *Server) listSubscriptions(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list(w, r, s.subscriptions.objects, s.renderSubscription)
+}
+
+func (s *Server) getSubscription(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription := s.subscriptions.get(params[0])
+	if subscription == nil {
+		writeNotFound(w, "subscription", params[0])
+		return
+	}
+	writeObject(w, http.StatusOK, s.renderSubscription(subscription))
+}
+
+//updateSubscription changes a subscription's plan. Moving to a pricier plan adds the price difference to the debt.
+func (s *Server) updateSubscription(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription := s.subscriptions.get(params[0])
+	if subscription == nil {
+		writeNotFound(w, "subscription", params[0])
+		return
+	}
+	if subscription["status"] == "canceled" {
+		writeInvalid(w, "subscription_id", "subscription is canceled")
+		return
+	}
+	plan := s.plans.get(r.Form.Get("plan_id"))
+	if plan == nil || plan["status"] != "active" {
+		writeInvalid(w, "plan_id", "plan not found")
+		return
+	}
+
+	previous := object{}
+	if plan["id"] != subscription["_plan_id"] {
+		oldPlan := subscription["plan"].(object)
+		oldPrice, _ := number(oldPlan["price"])
+		newPrice, _ := number(plan["price"])
+		if diff := int64(newPrice - oldPrice); diff > 0 {
+			previous["debt"] = subscription["debt"]
+			subscription["debt"] = subscription["debt"].(int64) + diff
+		}
+		previous["plan"] = oldPlan
+		subscription["plan"] = snapshot(plan)
+		subscription["_plan_id"] = plan["id"]
+	}
+	subscription["updated_at"] = s.now()
+
+	rendered := s.renderSubscription(subscription)
+	s.emit("customer.subscription.updated", rendered, previous)
+	writeObject(w, http.StatusOK, rendered)
+}
+
+//cancelSubscription cancels a subscription right away, or at the end of its current period if cancel_at_period_end is true.
+func (s *Server) cancelSubscription(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription := s.subscriptions.get(params[0])
+	if subscription == nil {
+		writeNotFound(w, "subscription", params[0])
+		return
+	}
+	if subscription["status"] == "canceled" {
+		writeInvalid(w, "subscription_id", "subscription is already canceled")
+		return
+	}
+
+	now := s.now()
+	previous := object{"status": subscription["status"], "end": subscription["end"]}
+	atPeriodEnd, _ := strconv.ParseBool(r.Form.Get("cancel_at_period_end"))
+	if atPeriodEnd && subscription["status"] != "inactive" {
+		subscription["end"] = subscription["current_period_end"]
+	} else {
+		subscription["status"] = "canceled"
+		subscription["end"] = now
+	}
+	subscription["updated_at"] = now
+
+	s.emit("customer.subscription.deleted", s.renderSubscription(subscription), previous)
+	w.WriteHeader(http.StatusNoContent)
+}
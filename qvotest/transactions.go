@@ -0,0 +1,190 @@
+package qvotest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//WebpayTTL is how long a webpay transaction may be completed.
+const WebpayTTL = InscriptionTTL
+
+func (s *Server) chargeCard(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	card := s.customerCard(w, params[0], params[1])
+	if card == nil {
+		return
+	}
+	amount, err := formInt(r, "amount", 0)
+	if err != nil || amount <= 0 {
+		writeInvalid(w, "amount", "amount must be a positive integer")
+		return
+	}
+
+	transaction := s.newTransaction(s.customers.get(params[0]), "webpay_oneclick", r.Form.Get("description"), amount)
+	s.transactions.add(transaction)
+	switch script := s.takeCharge(params[1]); {
+	case script == nil:
+		s.succeed(transaction, card)
+		s.emit("transaction.payment_succeeded", transaction, nil)
+	case script.reject != nil:
+		s.reject(transaction, card, *script.reject)
+		s.emit("transaction.payment_failed", transaction, nil)
+	default:
+		transaction["_timeout_at"] = s.clock().Add(script.timeout)
+	}
+	writeObject(w, http.StatusCreated, transaction)
+}
+
+//newTransaction returns a transaction waiting for the gateway's response.
+func (s *Server) newTransaction(customer object, gateway, description string, amount int64) object {
+	now := s.now()
+	return object{
+		"id":               s.newID("trx"),
+		"amount":           amount,
+		"currency":         "CLP",
+		"description":      description,
+		"gateway":          gateway,
+		"credits":          int64(0),
+		"status":           "waiting_for_response",
+		"customer":         snapshot(customer),
+		"payment":          nil,
+		"refund":           nil,
+		"gateway_response": object{"status": "", "message": ""},
+		"created_at":       now,
+		"updated_at":       now,
+		"_customer_id":     customer["id"],
+	}
+}
+
+//succeed marks a transaction as successfully paid with card, which may be nil for webpay plus.
+func (s *Server) succeed(transaction, card object) {
+	paymentType := "credit"
+	if card != nil && card["payment_type"] == "DB" {
+		paymentType = "debit"
+	}
+	transaction["status"] = "successful"
+	transaction["payment"] = object{
+		"amount":         transaction["amount"],
+		"gateway":        transaction["gateway"],
+		"payment_type":   paymentType,
+		"fee":            int64(0),
+		"installments":   int64(1),
+		"payment_method": card,
+	}
+	transaction["gateway_response"] = object{"status": "success", "message": "successful transaction"}
+	transaction["updated_at"] = s.now()
+}
+
+func (s *Server) listTransactions(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list(w, r, s.transactions.objects, same)
+}
+
+func (s *Server) getTransaction(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction := s.transactions.get(params[0])
+	if transaction == nil {
+		writeNotFound(w, "transaction", params[0])
+		return
+	}
+	writeObject(w, http.StatusOK, transaction)
+}
+
+//refundTransaction refunds the whole amount of a successful transaction.
+func (s *Server) refundTransaction(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction := s.transactions.get(params[0])
+	if transaction == nil {
+		writeNotFound(w, "transaction", params[0])
+		return
+	}
+	if transaction["status"] != "successful" {
+		writeInvalid(w, "transaction_id", fmt.Sprintf("can't refund a %s transaction", transaction["status"]))
+		return
+	}
+
+	now := s.now()
+	refund := object{"amount": transaction["amount"], "created_at": now}
+	transaction["status"] = "refunded"
+	transaction["refund"] = refund
+	transaction["updated_at"] = now
+
+	s.emit("transaction.refunded", transaction, object{"status": "successful"})
+	writeObject(w, http.StatusCreated, refund)
+}
+
+func (s *Server) createWebpay(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer := s.customers.get(r.Form.Get("customer_id"))
+	if customer == nil {
+		writeInvalid(w, "customer_id", "customer not found")
+		return
+	}
+	amount, err := formInt(r, "amount", 0)
+	if err != nil || amount <= 0 {
+		writeInvalid(w, "amount", "amount must be a positive integer")
+		return
+	}
+	returnURL := r.Form.Get("return_url")
+	if _, err := url.ParseRequestURI(returnURL); err != nil {
+		writeInvalid(w, "return_url", "return_url is invalid")
+		return
+	}
+
+	transaction := s.newTransaction(customer, "webpay_plus", r.Form.Get("Description"), amount)
+	transaction["_return_url"] = returnURL
+	s.transactions.add(transaction)
+	writeJSON(w, http.StatusCreated, object{
+		"transaction_id":  transaction["id"],
+		"redirect_url":    s.URL + "/webpay_plus/transactions/" + str(transaction["id"]),
+		"expiration_date": transaction["created_at"].(time.Time).Add(WebpayTTL),
+	})
+}
+
+//followWebpay completes a webpay transaction when its redirect url is followed, and redirects to the return url.
+func (s *Server) followWebpay(w http.ResponseWriter, r *http.Request, id string) {
+	returnURL, err := s.completeWebpay(id)
+	if err != nil {
+		writeNotFound(w, "transaction", id)
+		return
+	}
+	http.Redirect(w, r, returnURL, http.StatusFound)
+}
+
+//CompleteWebpay completes a webpay transaction as if the customer had paid.
+func (s *Server) CompleteWebpay(transactionID string) error {
+	_, err := s.completeWebpay(transactionID)
+	return err
+}
+
+//completeWebpay completes a webpay transaction, returning its return url.
+func (s *Server) completeWebpay(id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction := s.transactions.get(id)
+	if transaction == nil || transaction["gateway"] != "webpay_plus" {
+		return "", fmt.Errorf("qvotest: webpay transaction %s not found", id)
+	}
+	if transaction["status"] == "waiting_for_response" {
+		s.succeed(transaction, nil)
+		s.emit("transaction.payment_succeeded", transaction, object{"status": "waiting_for_response"})
+	}
+	return str(transaction["_return_url"]), nil
+}
+
+//same renders objects as they're stored.
+func same(o object) object {
+	return o
+}
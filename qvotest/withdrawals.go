@@ -0,0 +1,44 @@
+package qvotest
+
+import "net/http"
+
+func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, err := formInt(r, "amount", 0)
+	if err != nil || amount <= 0 {
+		writeInvalid(w, "amount", "amount must be a positive integer")
+		return
+	}
+
+	now := s.now()
+	withdrawal := object{
+		"id":         s.newID("wdr"),
+		"amount":     amount,
+		"status":     "processing",
+		"created_at": now,
+		"updated_at": now,
+	}
+	s.withdrawals.add(withdrawal)
+	s.emit("withdrawal.created", withdrawal, nil)
+	writeObject(w, http.StatusCreated, withdrawal)
+}
+
+func (s *Server) listWithdrawals(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list(w, r, s.withdrawals.objects, same)
+}
+
+func (s *Server) getWithdrawal(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	withdrawal := s.withdrawals.get(params[0])
+	if withdrawal == nil {
+		writeNotFound(w, "withdrawal", params[0])
+		return
+	}
+	writeObject(w, http.StatusOK, withdrawal)
+}
@@ -0,0 +1,150 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//TokenBucket is a token bucket rate limiter safe for concurrent use. It refills at rate tokens per second up to burst tokens.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+//NewTokenBucket returns a full bucket allowing rate requests per second with bursts of up to burst requests.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+//Wait blocks until a token is available or ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		wait := b.take(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+//take takes a token if there's one, returning 0. Otherwise it returns how long to wait before trying again.
+func (b *TokenBucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if b.rate <= 0 {
+		//Only a pause could have emptied a bucket without rate, so just check again soon.
+		return time.Second
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+//PauseUntil empties the bucket and holds every request until t.
+func (b *TokenBucket) PauseUntil(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.After(b.pausedUntil) {
+		b.pausedUntil = t
+		b.tokens = 0
+		b.last = t
+	}
+}
+
+//RateLimiter holds separate token buckets for read (GET) and write (POST, PUT, PATCH and DELETE) requests.
+//A nil bucket doesn't limit its requests. It may be shared by several clients using the same account.
+type RateLimiter struct {
+	Read  *TokenBucket
+	Write *TokenBucket
+}
+
+//NewRateLimiter returns a RateLimiter with the given rates (requests per second) and bursts. A rate <= 0 leaves those requests unlimited.
+func NewRateLimiter(readRate float64, readBurst int, writeRate float64, writeBurst int) *RateLimiter {
+	l := &RateLimiter{}
+	if readRate > 0 {
+		l.Read = NewTokenBucket(readRate, readBurst)
+	}
+	if writeRate > 0 {
+		l.Write = NewTokenBucket(writeRate, writeBurst)
+	}
+	return l
+}
+
+//WithRateLimiter makes the client wait for the limiter before every attempt of a request.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(c *Client) {
+		c.rateLimiter = l
+	}
+}
+
+//bucket returns the bucket for the given method.
+func (l *RateLimiter) bucket(method string) *TokenBucket {
+	if method == "GET" {
+		return l.Read
+	}
+	return l.Write
+}
+
+//Wait blocks until a request with the given method may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, method string) error {
+	if b := l.bucket(method); b != nil {
+		return b.Wait(ctx)
+	}
+	return nil
+}
+
+//Observe adapts the limiter to the rate limit headers of a response: on a 429, or when no requests remain, requests with the same method class are held until the limit resets.
+func (l *RateLimiter) Observe(method string, statusCode int, header http.Header) {
+	b := l.bucket(method)
+	if b == nil || header == nil {
+		return
+	}
+
+	rl := parseRateLimit(header)
+	sentRemaining := header.Get("X-RateLimit-Remaining") != "" || header.Get("RateLimit-Remaining") != ""
+	exhausted := statusCode == http.StatusTooManyRequests || (sentRemaining && rl.Remaining == 0)
+	if !exhausted {
+		return
+	}
+
+	until := rl.Reset
+	if retryAfter := parseRetryAfter(header); retryAfter > 0 {
+		until = time.Now().Add(retryAfter)
+	}
+	if until.IsZero() {
+		until = time.Now().Add(time.Second)
+	}
+	b.PauseUntil(until)
+}
@@ -0,0 +1,97 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimiter(t *testing.T) {
+	Convey("Given a token bucket", t, func() {
+		b := NewTokenBucket(10, 2)
+
+		Convey("The burst should be available right away and then refill at the rate", func() {
+			now := time.Now()
+			So(b.take(now), ShouldEqual, 0)
+			So(b.take(now), ShouldEqual, 0)
+			So(b.take(now), ShouldBeBetween, 99*time.Millisecond, 101*time.Millisecond)
+			So(b.take(now.Add(100*time.Millisecond)), ShouldEqual, 0)
+		})
+
+		Convey("Waiting should respect the context", func() {
+			b.PauseUntil(time.Now().Add(time.Hour))
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			So(b.Wait(ctx) == context.DeadlineExceeded, ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a local server and a client with separate read and write budgets", t, func() {
+		var reads, writes int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				atomic.AddInt32(&reads, 1)
+			} else {
+				atomic.AddInt32(&writes, 1)
+			}
+			w.Write([]byte(`{"id": "cus_1"}`))
+		}))
+		defer srv.Close()
+
+		limiter := NewRateLimiter(1000, 1000, 20, 1)
+		c := NewClient("token", true, WithBaseURL(srv.URL), WithRateLimiter(limiter))
+
+		Convey("Concurrent writes should be throttled while reads aren't", func() {
+			start := time.Now()
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					GetCustomer(c, "cus_1")
+				}()
+				go func() {
+					defer wg.Done()
+					UpdateCustomer(c, "cus_1", "Ignacio Gómez", "test@manglar.cl", "")
+				}()
+			}
+			wg.Wait()
+
+			So(atomic.LoadInt32(&reads), ShouldEqual, 5)
+			So(atomic.LoadInt32(&writes), ShouldEqual, 5)
+			//1 write right away and 4 more at 20 per second.
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 190*time.Millisecond)
+		})
+	})
+
+	Convey("A 429 with rate limit headers should hold further requests until the reset", t, func() {
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&hits, 1) == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"id": "cus_1"}`))
+		}))
+		defer srv.Close()
+
+		limiter := NewRateLimiter(1000, 1000, 1000, 1000)
+		c := NewClient("token", true, WithBaseURL(srv.URL), WithRateLimiter(limiter))
+
+		_, err := GetCustomer(c, "cus_1")
+		So(err, ShouldNotBeNil)
+
+		start := time.Now()
+		_, err = GetCustomer(c, "cus_1")
+		So(err, ShouldBeNil)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 900*time.Millisecond)
+	})
+}
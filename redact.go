@@ -0,0 +1,134 @@
+package qvo
+
+import (
+	"regexp"
+	"strings"
+)
+
+//Redacted replaces masked values in dumps.
+const Redacted = "[REDACTED]"
+
+//DefaultRedactedFields are the form and JSON fields masked by DefaultRedactor.
+var DefaultRedactedFields = []string{"name", "email", "rut", "card_number", "cvv", "password", "token"}
+
+var (
+	authorizationRe = regexp.MustCompile(`(?im)^(authorization:[ \t]*)[^\r\n]*`)
+	emailRe         = regexp.MustCompile(`[A-Za-z0-9._+\-]+(@|%40)[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardDigitsRe    = regexp.MustCompile(`\b\d(?:[ \-]?\d){11,18}\b`)
+)
+
+//Redactor masks secrets and PII from request and response dumps: the authorization header, emails, card digits (all but the last 4) and the values of the given fields, whether form encoded or JSON.
+type Redactor struct {
+	fields  []string
+	formRe  *regexp.Regexp
+	jsonRe  *regexp.Regexp
+	secrets []string
+}
+
+//NewRedactor returns a Redactor masking the given fields, along with the authorization header, emails and card digits.
+func NewRedactor(fields ...string) *Redactor {
+	r := &Redactor{}
+	r.AddFields(fields...)
+	return r
+}
+
+//DefaultRedactor returns a Redactor masking DefaultRedactedFields.
+func DefaultRedactor() *Redactor {
+	return NewRedactor(DefaultRedactedFields...)
+}
+
+//AddFields adds fields whose values are masked.
+func (r *Redactor) AddFields(fields ...string) {
+	r.fields = append(r.fields, fields...)
+	if len(r.fields) == 0 {
+		r.formRe, r.jsonRe = nil, nil
+		return
+	}
+	quoted := make([]string, len(r.fields))
+	for i, field := range r.fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	names := strings.Join(quoted, "|")
+	r.formRe = regexp.MustCompile(`(^|[?&\s])(` + names + `)=[^&\s]*`)
+	r.jsonRe = regexp.MustCompile(`("(?:` + names + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[^,}\]\s]+)`)
+}
+
+//AddSecrets adds literal strings, such as api tokens, which are masked wherever they appear.
+func (r *Redactor) AddSecrets(secrets ...string) {
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+}
+
+//Redact returns s with every secret and PII masked.
+func (r *Redactor) Redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	s = authorizationRe.ReplaceAllString(s, "${1}"+Redacted)
+	if r.formRe != nil {
+		s = r.formRe.ReplaceAllString(s, "${1}${2}="+Redacted)
+		s = r.jsonRe.ReplaceAllString(s, `${1}"`+Redacted+`"`)
+	}
+	s = emailRe.ReplaceAllString(s, Redacted)
+	s = cardDigitsRe.ReplaceAllStringFunc(s, maskCardDigits)
+	return s
+}
+
+//maskCardDigits masks every digit but the last 4.
+func maskCardDigits(digits string) string {
+	masked := []byte(digits)
+	kept := 0
+	for i := len(masked) - 1; i >= 0; i-- {
+		if masked[i] < '0' || masked[i] > '9' {
+			continue
+		}
+		if kept < 4 {
+			kept++
+			continue
+		}
+		masked[i] = '*'
+	}
+	return string(masked)
+}
+
+//WithRedactor sets the Redactor used on request and response dumps. The client's token is always masked too.
+func WithRedactor(r *Redactor) Option {
+	return func(c *Client) {
+		c.redactor = r
+	}
+}
+
+//WithRedactedFields masks the given fields in dumps, on top of DefaultRedactedFields.
+func WithRedactedFields(fields ...string) Option {
+	return func(c *Client) {
+		if c.redactor == nil {
+			c.redactor = DefaultRedactor()
+		}
+		c.redactor.AddFields(fields...)
+	}
+}
+
+//WithResponseDump enables dumping responses along with requests at debug level. It's disabled by default.
+func WithResponseDump(enabled bool) Option {
+	return func(c *Client) {
+		c.dumpResponses = enabled
+	}
+}
+
+//defaultRedactor is used by clients that weren't created with NewClient.
+var defaultRedactor = DefaultRedactor()
+
+//redact masks a dump with the client's Redactor and token.
+func (c *Client) redact(dump []byte) string {
+	s := string(dump)
+	if c.Token != "" {
+		s = strings.ReplaceAll(s, c.Token, Redacted)
+	}
+	if c.redactor == nil {
+		return defaultRedactor.Redact(s)
+	}
+	return c.redactor.Redact(s)
+}
@@ -0,0 +1,80 @@
+package qvo
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedactor(t *testing.T) {
+	Convey("Given the default redactor", t, func() {
+		r := DefaultRedactor()
+
+		Convey("The authorization header should be masked", func() {
+			So(r.Redact("POST /customers HTTP/1.1\r\nAuthorization: Bearer: secret\r\n"), ShouldEqual, "POST /customers HTTP/1.1\r\nAuthorization: [REDACTED]\r\n")
+		})
+
+		Convey("Form and JSON fields should be masked", func() {
+			So(r.Redact("email=test%40manglar.cl&name=Ignacio+G%C3%B3mez&amount=1000"), ShouldEqual, "email=[REDACTED]&name=[REDACTED]&amount=1000")
+			So(r.Redact(`{"id":"cus_1","name":"Ignacio \"Nacho\" Gómez","email":"test@manglar.cl"}`), ShouldEqual, `{"id":"cus_1","name":"[REDACTED]","email":"[REDACTED]"}`)
+		})
+
+		Convey("Emails anywhere should be masked", func() {
+			So(r.Redact("amount=1000&description=test%40manglar.cl"), ShouldEqual, "amount=1000&description=[REDACTED]")
+			So(r.Redact("paid by test@manglar.cl"), ShouldEqual, "paid by [REDACTED]")
+		})
+
+		Convey("Card digits should be masked but the last 4", func() {
+			So(r.Redact("card 4051 8856 0044 6623 ok"), ShouldEqual, "card **** **** **** 6623 ok")
+			So(r.Redact(`"last_4_digits":"6623"`), ShouldEqual, `"last_4_digits":"6623"`)
+		})
+
+		Convey("Extra fields should be masked too", func() {
+			r.AddFields("description")
+			So(r.Redact("amount=1000&description=contract+12"), ShouldEqual, "amount=1000&description=[REDACTED]")
+		})
+	})
+
+	Convey("Given a local server and a client logging at debug level", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id": "cus_1", "name": "Ignacio Gómez", "email": "test@manglar.cl"}`))
+		}))
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		level := new(slog.LevelVar)
+		level.Set(slog.LevelDebug)
+		logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})))
+
+		Convey("Request dumps shouldn't leak the token nor PII", func() {
+			c := NewClient("super-secret-token", true, WithBaseURL(srv.URL), WithLogger(logger))
+			_, err := CreateCustomer(c, "Ignacio Gómez", "test@manglar.cl")
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, "qvo request dump")
+			So(buf.String(), ShouldNotContainSubstring, "qvo response dump")
+			So(buf.String(), ShouldNotContainSubstring, "super-secret-token")
+			So(buf.String(), ShouldNotContainSubstring, "manglar.cl")
+		})
+
+		Convey("Response dumps should be redacted too when enabled", func() {
+			c := NewClient("super-secret-token", true, WithBaseURL(srv.URL), WithLogger(logger), WithResponseDump(true))
+			customer, err := GetCustomer(c, "cus_1")
+			So(err, ShouldBeNil)
+			So(customer.Email, ShouldEqual, "test@manglar.cl")
+			So(buf.String(), ShouldContainSubstring, "qvo response dump")
+			So(buf.String(), ShouldNotContainSubstring, "manglar.cl")
+		})
+
+		Convey("Nothing should be dumped when debug logging is off", func() {
+			level.Set(slog.LevelInfo)
+			c := NewClient("super-secret-token", true, WithBaseURL(srv.URL), WithLogger(logger), WithResponseDump(true))
+			_, err := GetCustomer(c, "cus_1")
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldNotContainSubstring, "dump")
+		})
+	})
+}
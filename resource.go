@@ -0,0 +1,138 @@
+package qvo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+//resource describes an API resource whose objects decode into T. Services build on its typed helpers, so every resource gets the same behavior, and adding one only takes describing it.
+type resource[T any] struct {
+	path  string //Collection endpoint, e.g., "customers".
+	idKey string //Form key the id is also sent with, e.g., "customer_id".
+
+	//Nested resources also send their parent's id, e.g., a card's "customer_id".
+	parentKey string
+	parentID  string
+}
+
+//endpoint returns the endpoint of the object with the given id.
+func (r resource[T]) endpoint(id string) string {
+	return fmt.Sprintf("%s/%s", r.path, id)
+}
+
+//form returns a form holding the object's id, and its parent's.
+func (r resource[T]) form(id string) url.Values {
+	form := url.Values{}
+	if r.parentKey != "" {
+		form.Add(r.parentKey, r.parentID)
+	}
+	form.Add(r.idKey, id)
+	return form
+}
+
+//get retrieves an object given its id.
+func (r resource[T]) get(ctx context.Context, c *Client, id string) (T, error) {
+	return call[T](ctx, c, "GET", r.endpoint(id), r.form(id))
+}
+
+//create creates an object from form.
+func (r resource[T]) create(ctx context.Context, c *Client, form url.Values) (T, error) {
+	return call[T](ctx, c, "POST", r.path, form)
+}
+
+//update updates an object given its id with the fields in form.
+func (r resource[T]) update(ctx context.Context, c *Client, id string, form url.Values) (T, error) {
+	for k, v := range r.form(id) {
+		form[k] = v
+	}
+	return call[T](ctx, c, "PUT", r.endpoint(id), form)
+}
+
+//delete deletes an object given its id, sending any extra fields in form, which may be nil.
+func (r resource[T]) delete(ctx context.Context, c *Client, id string, form url.Values) error {
+	if form == nil {
+		form = url.Values{}
+	}
+	for k, v := range r.form(id) {
+		form[k] = v
+	}
+	_, err := c.request(ctx, "DELETE", r.endpoint(id), form)
+	return err
+}
+
+//list retrieves a list of objects with given pages, filters and order.
+func (r resource[T]) list(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]T, error) {
+
+	var objects = make([]T, 0)
+
+	form := url.Values{}
+	if page > 0 && perPage > 0 {
+		form.Add("page", strconv.Itoa(page))
+		form.Add("per_page", strconv.Itoa(perPage))
+	}
+
+	if len(where) > 0 {
+		jBytes, err := json.Marshal(where)
+		if err != nil {
+			c.log(LevelError, "errored at where", Fields{"error": err})
+			return objects, err
+		}
+		form.Add("where", string(jBytes))
+	}
+
+	if orderBy != "" {
+		form.Add("order_by", orderBy)
+	}
+
+	body, err := c.request(ctx, "GET", r.path, form)
+	if err != nil {
+		c.log(LevelError, "errored at body", Fields{"error": err})
+		return objects, err
+	}
+
+	err = json.Unmarshal(body, &objects)
+
+	if err != nil {
+		c.log(LevelError, "errored at unmarshal", Fields{"error": err})
+		return make([]T, 0), err
+	}
+
+	return objects, nil
+
+}
+
+//listByQuery retrieves a list of objects matching a query, after checking its fields are fields of T.
+func (r resource[T]) listByQuery(ctx context.Context, c *Client, q *Query) ([]T, error) {
+
+	var zero T
+	page, perPage, where, orderBy, err := q.build(zero)
+	if err != nil {
+		c.log(LevelError, "errored at query", Fields{"error": err})
+		return make([]T, 0), err
+	}
+
+	return r.list(ctx, c, page, perPage, where, orderBy)
+
+}
+
+//call makes a request and decodes its response into a T.
+func call[T any](ctx context.Context, c *Client, method, endpoint string, form url.Values) (T, error) {
+	var v T
+	if err := c.call(ctx, method, endpoint, form, &v); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+//call makes a request and decodes its response into v.
+func (c *Client) call(ctx context.Context, method, endpoint string, form url.Values, v interface{}) error {
+	body, err := c.request(ctx, method, endpoint, form)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
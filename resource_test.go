@@ -0,0 +1,95 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResource(t *testing.T) {
+	Convey("Given a local server recording requests and a client", t, func() {
+		var gotMethod, gotPath string
+		var gotForm url.Values
+		status, body := http.StatusOK, `{"id": "thing_1"}`
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			gotMethod, gotPath, gotForm = r.Method, r.URL.Path, r.Form
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+		defer srv.Close()
+		c := NewClient("token", true, WithBaseURL(srv.URL))
+		ctx := context.Background()
+
+		type thing struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		things := resource[thing]{path: "things", idKey: "thing_id"}
+
+		Convey("Any resource should get, update and delete by id", func() {
+			got, err := things.get(ctx, c, "thing_1")
+			So(err, ShouldBeNil)
+			So(got.ID, ShouldEqual, "thing_1")
+			So(gotMethod, ShouldEqual, "GET")
+			So(gotPath, ShouldEqual, "/things/thing_1")
+			So(gotForm.Get("thing_id"), ShouldEqual, "thing_1")
+
+			_, err = things.update(ctx, c, "thing_1", url.Values{"name": {"new"}})
+			So(err, ShouldBeNil)
+			So(gotMethod, ShouldEqual, "PUT")
+			So(gotForm.Get("name"), ShouldEqual, "new")
+			So(gotForm.Get("thing_id"), ShouldEqual, "thing_1")
+
+			So(things.delete(ctx, c, "thing_1", nil), ShouldBeNil)
+			So(gotMethod, ShouldEqual, "DELETE")
+		})
+
+		Convey("Nested resources should send their parent's id", func() {
+			_, err := cardResource("cus_1").get(ctx, c, "card_1")
+			So(err, ShouldBeNil)
+			So(gotPath, ShouldEqual, "/customers/cus_1/cards/card_1")
+			So(gotForm.Get("customer_id"), ShouldEqual, "cus_1")
+			So(gotForm.Get("card_id"), ShouldEqual, "card_1")
+		})
+
+		Convey("A customer's cards should be listed from its cards endpoint", func() {
+			body = `[{"id": "card_1", "last_4_digits": "4242"}]`
+			cards, err := ListCards(c, "cus_1")
+			So(err, ShouldBeNil)
+			So(cards, ShouldHaveLength, 1)
+			So(cards[0].Lats4Digits, ShouldEqual, "4242")
+			So(gotMethod, ShouldEqual, "GET")
+			So(gotPath, ShouldEqual, "/customers/cus_1/cards")
+		})
+
+		Convey("Lists should send pagination, filters and order", func() {
+			body = `[{"id": "thing_1"}, {"id": "thing_2"}]`
+			list, err := things.list(ctx, c, 2, 10, map[string]map[string]interface{}{"name": {"=": "a"}}, "name ASC")
+			So(err, ShouldBeNil)
+			So(list, ShouldHaveLength, 2)
+			So(gotForm.Get("page"), ShouldEqual, "2")
+			So(gotForm.Get("per_page"), ShouldEqual, "10")
+			So(gotForm.Get("where"), ShouldEqual, `{"name":{"=":"a"}}`)
+			So(gotForm.Get("order_by"), ShouldEqual, "name ASC")
+		})
+
+		Convey("Failures should return zero values", func() {
+			status, body = http.StatusNotFound, `{"error": {"type": "not_found", "message": "no such thing"}}`
+			got, err := things.get(ctx, c, "thing_2")
+			So(err, ShouldNotBeNil)
+			So(got, ShouldResemble, thing{})
+
+			body = `{"id": "not a list"}`
+			status = http.StatusOK
+			list, err := things.list(ctx, c, 0, 0, nil, "")
+			So(err, ShouldNotBeNil)
+			So(list, ShouldNotBeNil)
+			So(list, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,133 @@
+package qvo
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//RetryPolicy configures how the client retries transient failures: network errors, 429s and 5xx responses.
+//Only GET and DELETE requests are retried, unless RetryIdempotentPOST is set, which allows retrying POST, PUT and PATCH requests carrying an idempotency key.
+type RetryPolicy struct {
+	MaxAttempts         int           //Total attempts, including the first one. Retries are disabled when <= 1.
+	InitialBackoff      time.Duration //Wait before the first retry.
+	MaxBackoff          time.Duration //Upper bound for the exponential backoff.
+	Multiplier          float64       //Backoff growth factor between retries.
+	Jitter              float64       //Fraction of the backoff in [0, 1] that's randomized, to keep retrying clients from syncing up.
+	MaxRetryAfter       time.Duration //Retry-After waits longer than this aren't honored and the error is returned instead. 0 means no limit.
+	RetryIdempotentPOST bool          //Retry mutating requests that carry an idempotency key.
+	OnRetry             func(RetryInfo)
+}
+
+//RetryInfo describes a failed attempt that's about to be retried. It's passed to RetryPolicy.OnRetry for logging or metrics.
+type RetryInfo struct {
+	Method     string
+	Endpoint   string
+	Attempt    int //The attempt that failed, starting at 1.
+	StatusCode int //0 when the attempt failed before getting a response.
+	Err        error
+	Wait       time.Duration //Time until the next attempt.
+}
+
+//DefaultRetryPolicy returns a policy with 3 attempts and an exponential backoff starting at 250ms. Clients don't retry unless it's set with WithRetryPolicy.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxRetryAfter:  time.Minute,
+	}
+}
+
+//WithRetryPolicy sets the retry policy for the client.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+//allowsMethod tells if a request with the given method (and idempotency key, if any) may be retried.
+func (p RetryPolicy) allowsMethod(method string, hasIdempotencyKey bool) bool {
+	switch method {
+	case "GET", "DELETE":
+		return true
+	case "POST", "PUT", "PATCH":
+		return p.RetryIdempotentPOST && hasIdempotencyKey
+	}
+	return false
+}
+
+//backoff returns the wait before the retry following the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		wait = wait * (1 - jitter + 2*jitter*rand.Float64())
+	}
+	return time.Duration(wait)
+}
+
+//isRetryable tells if an attempt failed for a transient reason. Errors caused by ctx being done never are.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+
+	//Per attempt timeouts, connection errors and bodies cut short.
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	return false
+}
+
+//parseRetryAfter reads a Retry-After header, given either in seconds or as an HTTP date. It returns 0 if missing or invalid.
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+//sleep waits for d or until ctx is done, whichever happens first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
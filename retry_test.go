@@ -0,0 +1,107 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("Given a local server failing the first two attempts", t, func() {
+
+		var hits int32
+		var lastKey string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastKey = r.Header.Get(IdempotencyKeyHeader)
+			if atomic.AddInt32(&hits, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error": {"type": "api_error", "message": "unavailable"}}`))
+				return
+			}
+			w.Write([]byte(`{"id": "trx_1", "amount": 1000, "status": "successful"}`))
+		}))
+		defer srv.Close()
+
+		policy := DefaultRetryPolicy()
+		policy.InitialBackoff = time.Millisecond
+		var retries []RetryInfo
+		policy.OnRetry = func(info RetryInfo) {
+			retries = append(retries, info)
+		}
+
+		Convey("A GET should be retried until it succeeds", func() {
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithRetryPolicy(policy))
+
+			transaction, err := GetTransaction(c, "trx_1")
+			So(err, ShouldBeNil)
+			So(transaction.Status, ShouldEqual, Successful)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 3)
+			So(retries, ShouldHaveLength, 2)
+			So(retries[0].Attempt, ShouldEqual, 1)
+			So(retries[0].StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			So(retries[0].Endpoint, ShouldEqual, "transactions/trx_1")
+		})
+
+		Convey("A GET should give up after the max attempts", func() {
+			policy.MaxAttempts = 2
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithRetryPolicy(policy))
+
+			_, err := GetTransaction(c, "trx_1")
+			So(errors.Is(err, ErrServer), ShouldBeTrue)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 2)
+		})
+
+		Convey("A POST without idempotency key shouldn't be retried", func() {
+			policy.RetryIdempotentPOST = true
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithRetryPolicy(policy), WithAutoIdempotencyKeys(false))
+
+			_, err := ChargeCard(c, "cus_1", "card_1", "test", 1000)
+			So(errors.Is(err, ErrServer), ShouldBeTrue)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+		})
+
+		Convey("A POST with idempotency key should be retried with the same key when allowed", func() {
+			policy.RetryIdempotentPOST = true
+			c := NewClient("token", true, WithBaseURL(srv.URL), WithRetryPolicy(policy))
+
+			ctx := WithIdempotencyKey(context.Background(), "charge-1")
+			_, err := ChargeCardWithContext(ctx, c, "cus_1", "card_1", "test", 1000)
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&hits), ShouldEqual, 3)
+			So(lastKey, ShouldEqual, "charge-1")
+		})
+
+	})
+
+	Convey("Retry-After should be parsed as seconds or date", t, func() {
+		header := http.Header{}
+		So(parseRetryAfter(header), ShouldEqual, 0)
+
+		header.Set("Retry-After", "3")
+		So(parseRetryAfter(header), ShouldEqual, 3*time.Second)
+
+		header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		So(parseRetryAfter(header), ShouldBeBetween, 59*time.Minute, time.Hour)
+	})
+
+	Convey("A Retry-After longer than the allowed one should stop retries", t, func() {
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		c := NewClient("token", true, WithBaseURL(srv.URL), WithRetryPolicy(DefaultRetryPolicy()))
+		_, err := GetTransaction(c, "trx_1")
+		So(errors.Is(err, ErrRateLimited), ShouldBeTrue)
+		So(atomic.LoadInt32(&hits), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,65 @@
+package qvo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//fakeCustomers is a CustomerService standing in for the API.
+type fakeCustomers struct {
+	CustomerService
+	created []string
+}
+
+func (f *fakeCustomers) Create(ctx context.Context, name, email string) (Customer, error) {
+	f.created = append(f.created, email)
+	return Customer{ID: "cus_fake", Name: name, Email: email}, nil
+}
+
+func TestServices(t *testing.T) {
+	Convey("Given a client whose customer service was replaced with a fake", t, func() {
+		c := NewClient("token", true, WithBaseURL("http://127.0.0.1:0"))
+		fake := &fakeCustomers{}
+		c.Customers = fake
+
+		Convey("Both the service and the package functions should use the fake", func() {
+			customer, err := c.Customers.Create(context.Background(), "Ignacio Gómez", "test@manglar.cl")
+			So(err, ShouldBeNil)
+			So(customer.ID, ShouldEqual, "cus_fake")
+
+			customer, err = CreateCustomer(c, "Ignacio Gómez", "other@manglar.cl")
+			So(err, ShouldBeNil)
+			So(customer.ID, ShouldEqual, "cus_fake")
+			So(fake.created, ShouldResemble, []string{"test@manglar.cl", "other@manglar.cl"})
+		})
+	})
+
+	Convey("Given a local server and a client", t, func() {
+		var gotPath string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			w.Write([]byte(`{"id": "pln_1", "name": "Basic"}`))
+		}))
+		defer srv.Close()
+
+		Convey("Services should call the API", func() {
+			c := NewClient("token", true, WithBaseURL(srv.URL))
+			plan, err := c.Plans.Get(context.Background(), "pln_1")
+			So(err, ShouldBeNil)
+			So(plan.Name, ShouldEqual, "Basic")
+			So(gotPath, ShouldEqual, "/plans/pln_1")
+		})
+
+		Convey("Clients not created with NewClient should still work through the package functions", func() {
+			c := &Client{Token: "token", baseURL: srv.URL}
+			So(c.Plans, ShouldBeNil)
+			plan, err := GetPlan(c, "pln_1")
+			So(err, ShouldBeNil)
+			So(plan.ID, ShouldEqual, "pln_1")
+		})
+	})
+}
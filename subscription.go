@@ -1,15 +1,12 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 //Subscription struct to represent a qvo subscription object.
@@ -31,14 +28,48 @@ type Subscription struct {
 	UpdatedAt          time.Time     `json:"updated_at"`
 }
 
-//CreateSubscription creates a subscription for a customer and plan. Returns a copy of the subscription if successful, and an error if not.
+//SubscriptionService handles subscriptions. A Client exposes it as its Subscriptions field, which may be replaced with a fake in tests.
+type SubscriptionService interface {
+	Create(ctx context.Context, customerID, planID, taxName string, taxPercent float64, cycleCount int64, start *time.Time) (Subscription, error)
+	Get(ctx context.Context, subscriptionID string) (Subscription, error)
+	Update(ctx context.Context, subscriptionID, planID string) (Subscription, error)
+	Cancel(ctx context.Context, subscriptionID string, cancelAtePeriodEnd bool) error
+	List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Subscription, error)
+	ListByQuery(ctx context.Context, q *Query) ([]Subscription, error)
+}
+
+//subscriptionResource is the subscriptions endpoint.
+var subscriptionResource = resource[Subscription]{path: "subscriptions", idKey: "subscription_id"}
+
+//subscriptionService implements SubscriptionService through a Client.
+type subscriptionService struct {
+	c *Client
+}
+
+//subscriptions returns the client's SubscriptionService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) subscriptions() SubscriptionService {
+	if c.Subscriptions != nil {
+		return c.Subscriptions
+	}
+	return &subscriptionService{c: c}
+}
+
+//CreateSubscription calls CreateSubscriptionWithContext with a background context.
+func CreateSubscription(c *Client, customerID, planID, taxName string, taxPercent float64, cycleCount int64, start *time.Time) (Subscription, error) {
+	return CreateSubscriptionWithContext(context.Background(), c, customerID, planID, taxName, taxPercent, cycleCount, start)
+}
+
+//CreateSubscriptionWithContext calls Create on the client's SubscriptionService.
+func CreateSubscriptionWithContext(ctx context.Context, c *Client, customerID, planID, taxName string, taxPercent float64, cycleCount int64, start *time.Time) (Subscription, error) {
+	return c.subscriptions().Create(ctx, customerID, planID, taxName, taxPercent, cycleCount, start)
+}
+
+//Create creates a subscription for a customer and plan. Returns a copy of the subscription if successful, and an error if not.
 //customerID and planID are required.
 //cycleCount <= 0 will be omitted.
 //If taxName is "" or taxPercent isn´t in [0.0, 100.0], they'll be omitted.
 //start is a pointer to a time.Time, so a nil pointer will be omitted.
-func CreateSubscription(c *Client, customerID, planID, taxName string, taxPercent float64, cycleCount int64, start *time.Time) (Subscription, error) {
-
-	var subscription Subscription
+func (s *subscriptionService) Create(ctx context.Context, customerID, planID, taxName string, taxPercent float64, cycleCount int64, start *time.Time) (Subscription, error) {
 
 	//Validate required fields.
 	if customerID == "" {
@@ -53,7 +84,7 @@ func CreateSubscription(c *Client, customerID, planID, taxName string, taxPercen
 	form.Add("customer_id", customerID)
 	form.Add("plan_id", planID)
 	if start != nil {
-		form.Add("start", (*start).Format("2006-01-02T15:04:05.999Z"))
+		form.Add("start", (*start).Format(timeFormat))
 	}
 	if cycleCount > 0 {
 		form.Add("cycle_count", strconv.FormatInt(cycleCount, 10))
@@ -63,125 +94,90 @@ func CreateSubscription(c *Client, customerID, planID, taxName string, taxPercen
 		form.Add("tax_percent", strconv.FormatFloat(taxPercent, 'f', -1, 64))
 	}
 
-	body, err := c.request("POST", "subscriptions", form)
-	if err != nil {
-		return Subscription{}, err
-	}
-
-	err = json.Unmarshal(body, &subscription)
-
-	if err != nil {
-		return Subscription{}, err
-	}
-
-	return subscription, nil
+	return subscriptionResource.create(ctx, s.c, form)
 }
 
-//GetSubscription returns the subscription or an error.
+//GetSubscription calls GetSubscriptionWithContext with a background context.
 func GetSubscription(c *Client, subscriptionID string) (Subscription, error) {
-	endpoint := fmt.Sprintf("subscriptions/%s", subscriptionID)
-
-	form := url.Values{}
-	form.Add("subscription_id", subscriptionID)
-
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Subscription{}, err
-	}
-
-	var subscription Subscription
-	err = json.Unmarshal(body, &subscription)
+	return GetSubscriptionWithContext(context.Background(), c, subscriptionID)
+}
 
-	if err != nil {
-		return Subscription{}, err
-	}
+//GetSubscriptionWithContext calls Get on the client's SubscriptionService.
+func GetSubscriptionWithContext(ctx context.Context, c *Client, subscriptionID string) (Subscription, error) {
+	return c.subscriptions().Get(ctx, subscriptionID)
+}
 
-	return subscription, nil
+//Get returns the subscription or an error.
+func (s *subscriptionService) Get(ctx context.Context, subscriptionID string) (Subscription, error) {
+	return subscriptionResource.get(ctx, s.c, subscriptionID)
 }
 
-//UpdateSubscription updates a subscription's plan given its id.
+//UpdateSubscription calls UpdateSubscriptionWithContext with a background context.
 func UpdateSubscription(c *Client, subscriptionID, planID string) (Subscription, error) {
+	return UpdateSubscriptionWithContext(context.Background(), c, subscriptionID, planID)
+}
 
-	endpoint := fmt.Sprintf("subscriptions/%s", subscriptionID)
+//UpdateSubscriptionWithContext calls Update on the client's SubscriptionService.
+func UpdateSubscriptionWithContext(ctx context.Context, c *Client, subscriptionID, planID string) (Subscription, error) {
+	return c.subscriptions().Update(ctx, subscriptionID, planID)
+}
+
+//Update updates a subscription's plan given its id.
+func (s *subscriptionService) Update(ctx context.Context, subscriptionID, planID string) (Subscription, error) {
 
 	form := url.Values{}
-	form.Add("subscription_id", subscriptionID)
 	form.Add("plan_id", planID)
 
-	body, err := c.request("PUT", endpoint, form)
-	if err != nil {
-		return Subscription{}, err
-	}
-
-	var subscription Subscription
-	err = json.Unmarshal(body, &subscription)
-
-	if err != nil {
-		return Subscription{}, err
-	}
+	return subscriptionResource.update(ctx, s.c, subscriptionID, form)
+}
 
-	return subscription, nil
+//CancelSubscription calls CancelSubscriptionWithContext with a background context.
+func CancelSubscription(c *Client, subscriptionID string, cancelAtePeriodEnd bool) error {
+	return CancelSubscriptionWithContext(context.Background(), c, subscriptionID, cancelAtePeriodEnd)
+}
 
+//CancelSubscriptionWithContext calls Cancel on the client's SubscriptionService.
+func CancelSubscriptionWithContext(ctx context.Context, c *Client, subscriptionID string, cancelAtePeriodEnd bool) error {
+	return c.subscriptions().Cancel(ctx, subscriptionID, cancelAtePeriodEnd)
 }
 
-//CancelSubscription cancels a subscription.
+//Cancel cancels a subscription.
 //Depending on cancelAtPeriodEnd, it'll be canceled when the current period end is reached (if true), or immediately (if false).
 //If subscription was ianctive, it'll be canceled immediately anyway.
-func CancelSubscription(c *Client, subscriptionID string, cancelAtePeriodEnd bool) error {
-
-	endpoint := fmt.Sprintf("subscriptions/%s", subscriptionID)
+func (s *subscriptionService) Cancel(ctx context.Context, subscriptionID string, cancelAtePeriodEnd bool) error {
 
 	form := url.Values{}
-	form.Add("subscription_id", subscriptionID)
 	form.Add("cancel_at_period_end", strconv.FormatBool(cancelAtePeriodEnd))
 
-	_, err := c.request("DELETE", endpoint, form)
-	if err != nil {
-		return err
-	}
-
-	return nil
-
+	return subscriptionResource.delete(ctx, s.c, subscriptionID, form)
 }
 
-//ListSubscriptions retrieves a list of subscriptions with given pages, filters and order.
+//ListSubscriptions calls ListSubscriptionsWithContext with a background context.
 func ListSubscriptions(c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Subscription, error) {
+	return ListSubscriptionsWithContext(context.Background(), c, page, perPage, where, orderBy)
+}
 
-	var subscriptions = make([]Subscription, 0)
-
-	form := url.Values{}
-	if page > 0 && perPage > 0 {
-		form.Add("page", strconv.Itoa(page))
-		form.Add("per_page", strconv.Itoa(perPage))
-	}
-
-	if len(where) > 0 {
-		jBytes, err := json.Marshal(where)
-		if err != nil {
-			log.Errorf("errored at where: %s", err)
-			return subscriptions, err
-		}
-		form.Add("where", string(jBytes))
-	}
-
-	if orderBy != "" {
-		form.Add("order_by", orderBy)
-	}
-
-	body, err := c.request("GET", "subscriptions", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return subscriptions, err
-	}
+//ListSubscriptionsWithContext calls List on the client's SubscriptionService.
+func ListSubscriptionsWithContext(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Subscription, error) {
+	return c.subscriptions().List(ctx, page, perPage, where, orderBy)
+}
 
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&subscriptions)
+//List retrieves a list of subscriptions with given pages, filters and order.
+func (s *subscriptionService) List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Subscription, error) {
+	return subscriptionResource.list(ctx, s.c, page, perPage, where, orderBy)
+}
 
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return subscriptions, err
-	}
+//ListSubscriptionsByQuery calls ListSubscriptionsByQueryWithContext with a background context.
+func ListSubscriptionsByQuery(c *Client, q *Query) ([]Subscription, error) {
+	return ListSubscriptionsByQueryWithContext(context.Background(), c, q)
+}
 
-	return subscriptions, nil
+//ListSubscriptionsByQueryWithContext calls ListByQuery on the client's SubscriptionService.
+func ListSubscriptionsByQueryWithContext(ctx context.Context, c *Client, q *Query) ([]Subscription, error) {
+	return c.subscriptions().ListByQuery(ctx, q)
+}
 
+//ListByQuery retrieves a list of subscriptions matching a query, after checking its fields are subscription fields.
+func (s *subscriptionService) ListByQuery(ctx context.Context, q *Query) ([]Subscription, error) {
+	return subscriptionResource.listByQuery(ctx, s.c, q)
 }
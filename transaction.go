@@ -1,14 +1,9 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/url"
-	"strconv"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 //Status constants
@@ -53,90 +48,88 @@ type Transaction struct {
 	UpdatedAt       time.Time               `json:"updated_at"`
 }
 
-//GetTransaction retrieves a transaction by id.
-func GetTransaction(c *Client, id string) (Transaction, error) {
+//TransactionService handles transactions. A Client exposes it as its Transactions field, which may be replaced with a fake in tests.
+type TransactionService interface {
+	Get(ctx context.Context, id string) (Transaction, error)
+	Refund(ctx context.Context, id string) (Refund, error)
+	List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Transaction, error)
+	ListByQuery(ctx context.Context, q *Query) ([]Transaction, error)
+}
 
-	endpoint := fmt.Sprintf("transactions/%s", id)
+//transactionResource is the transactions endpoint.
+var transactionResource = resource[Transaction]{path: "transactions", idKey: "transaction_id"}
 
-	form := url.Values{}
-	form.Add("transaction_id", id)
+//transactionService implements TransactionService through a Client.
+type transactionService struct {
+	c *Client
+}
 
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Transaction{}, err
+//transactions returns the client's TransactionService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) transactions() TransactionService {
+	if c.Transactions != nil {
+		return c.Transactions
 	}
+	return &transactionService{c: c}
+}
 
-	var transaction Transaction
-	err = json.Unmarshal(body, &transaction)
-
-	if err != nil {
-		return Transaction{}, err
-	}
+//GetTransaction calls GetTransactionWithContext with a background context.
+func GetTransaction(c *Client, id string) (Transaction, error) {
+	return GetTransactionWithContext(context.Background(), c, id)
+}
 
-	return transaction, nil
+//GetTransactionWithContext calls Get on the client's TransactionService.
+func GetTransactionWithContext(ctx context.Context, c *Client, id string) (Transaction, error) {
+	return c.transactions().Get(ctx, id)
+}
 
+//Get retrieves a transaction by id.
+func (s *transactionService) Get(ctx context.Context, id string) (Transaction, error) {
+	return transactionResource.get(ctx, s.c, id)
 }
 
-//RefundTransaction makes a refund request for a given transaction id.
+//RefundTransaction calls RefundTransactionWithContext with a background context.
 func RefundTransaction(c *Client, id string) (Refund, error) {
-	endpoint := fmt.Sprintf("transactions/%s/refund", id)
-
-	form := url.Values{}
-	form.Add("transaction_id", id)
-
-	body, err := c.request("POST", endpoint, form)
-	if err != nil {
-		return Refund{}, err
-	}
+	return RefundTransactionWithContext(context.Background(), c, id)
+}
 
-	var refund Refund
-	err = json.Unmarshal(body, &refund)
+//RefundTransactionWithContext calls Refund on the client's TransactionService.
+func RefundTransactionWithContext(ctx context.Context, c *Client, id string) (Refund, error) {
+	return c.transactions().Refund(ctx, id)
+}
 
-	if err != nil {
-		return Refund{}, err
-	}
+//Refund makes a refund request for a given transaction id.
+func (s *transactionService) Refund(ctx context.Context, id string) (Refund, error) {
+	endpoint := fmt.Sprintf("transactions/%s/refund", id)
 
-	return refund, nil
+	return call[Refund](ctx, s.c, "POST", endpoint, transactionResource.form(id))
 }
 
-//ListTransactions retrieves a list of transactions with given pages, filters and order.
+//ListTransactions calls ListTransactionsWithContext with a background context.
 func ListTransactions(c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Transaction, error) {
+	return ListTransactionsWithContext(context.Background(), c, page, perPage, where, orderBy)
+}
 
-	var transactions = make([]Transaction, 0)
-
-	form := url.Values{}
-	if page > 0 && perPage > 0 {
-		form.Add("page", strconv.Itoa(page))
-		form.Add("per_page", strconv.Itoa(perPage))
-	}
-
-	if len(where) > 0 {
-		jBytes, err := json.Marshal(where)
-		if err != nil {
-			log.Errorf("errored at where: %s", err)
-			return transactions, err
-		}
-		form.Add("where", string(jBytes))
-	}
-
-	if orderBy != "" {
-		form.Add("order_by", orderBy)
-	}
-
-	body, err := c.request("GET", "transactions", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return transactions, err
-	}
+//ListTransactionsWithContext calls List on the client's TransactionService.
+func ListTransactionsWithContext(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Transaction, error) {
+	return c.transactions().List(ctx, page, perPage, where, orderBy)
+}
 
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&transactions)
+//List retrieves a list of transactions with given pages, filters and order.
+func (s *transactionService) List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Transaction, error) {
+	return transactionResource.list(ctx, s.c, page, perPage, where, orderBy)
+}
 
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return transactions, err
-	}
+//ListTransactionsByQuery calls ListTransactionsByQueryWithContext with a background context.
+func ListTransactionsByQuery(c *Client, q *Query) ([]Transaction, error) {
+	return ListTransactionsByQueryWithContext(context.Background(), c, q)
+}
 
-	return transactions, nil
+//ListTransactionsByQueryWithContext calls ListByQuery on the client's TransactionService.
+func ListTransactionsByQueryWithContext(ctx context.Context, c *Client, q *Query) ([]Transaction, error) {
+	return c.transactions().ListByQuery(ctx, q)
+}
 
+//ListByQuery retrieves a list of transactions matching a query, after checking its fields are transaction fields.
+func (s *transactionService) ListByQuery(ctx context.Context, q *Query) ([]Transaction, error) {
+	return transactionResource.listByQuery(ctx, s.c, q)
 }
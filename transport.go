@@ -0,0 +1,77 @@
+package qvo
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+//TransportConfig holds the connection pooling settings for the transport a Client owns.
+type TransportConfig struct {
+	MaxIdleConns          int           //Idle connections kept across all hosts.
+	MaxIdleConnsPerHost   int           //Idle connections kept to QVO's host. Go's default of 2 is too low for concurrent callers.
+	MaxConnsPerHost       int           //0 means no limit.
+	IdleConnTimeout       time.Duration //How long an idle connection is kept in the pool.
+	KeepAlive             time.Duration //TCP keep-alive period.
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ExpectContinueTimeout time.Duration
+	ForceAttemptHTTP2     bool
+}
+
+//DefaultTransportConfig returns the transport settings used by NewClient when no http client, transport or config is given.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
+		IdleConnTimeout:       90 * time.Second,
+		KeepAlive:             30 * time.Second,
+		DialTimeout:           10 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+}
+
+//WithTransportConfig tunes the transport the client builds for itself.
+//It's ignored if WithHTTPClient or WithTransport are given.
+func WithTransportConfig(cfg TransportConfig) Option {
+	return func(c *Client) {
+		c.transportConfig = cfg
+	}
+}
+
+//newTransport builds an http.Transport from the given config. It's meant to be created once and shared by every request.
+func newTransport(cfg TransportConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: cfg.ExpectContinueTimeout,
+		ForceAttemptHTTP2:     cfg.ForceAttemptHTTP2,
+	}
+}
+
+//defaultHTTPClient is shared by clients that weren't created with NewClient, so they pool connections and keep the default timeout too.
+var defaultHTTPClient = &http.Client{Transport: newTransport(DefaultTransportConfig()), Timeout: DefaultTimeout}
+
+//CloseIdleConnections closes the idle connections kept by the client's transport.
+func (c *Client) CloseIdleConnections() {
+	c.getHTTPClient().CloseIdleConnections()
+}
+
+//getHTTPClient returns the client's http client, or the shared default one for clients not created with NewClient.
+func (c *Client) getHTTPClient() *http.Client {
+	if c.httpClient != nil {
+		return c.httpClient
+	}
+	return defaultHTTPClient
+}
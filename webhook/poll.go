@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/pkg/errors"
+)
+
+//Defaults for Poller.
+const (
+	DefaultPollInterval = 30 * time.Second
+	DefaultDedupTTL     = 24 * time.Hour
+)
+
+//Checkpoint is the last event a Poller delivered.
+type Checkpoint struct {
+	EventID   string    `json:"event_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+//CheckpointStore persists a Poller's checkpoint, so a restarted poller resumes where it stopped.
+type CheckpointStore interface {
+	//Load returns the saved checkpoint, or a zero one if none was saved.
+	Load(ctx context.Context) (Checkpoint, error)
+	//Save replaces the saved checkpoint.
+	Save(ctx context.Context, cp Checkpoint) error
+}
+
+//MemoryCheckpointStore keeps the checkpoint in memory, e.g., for tests or pollers that may start over. It's safe for concurrent use.
+type MemoryCheckpointStore struct {
+	mu sync.Mutex
+	cp Checkpoint
+}
+
+//Load returns the checkpoint.
+func (s *MemoryCheckpointStore) Load(ctx context.Context) (Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cp, nil
+}
+
+//Save replaces the checkpoint.
+func (s *MemoryCheckpointStore) Save(ctx context.Context, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cp = cp
+	return nil
+}
+
+//FileCheckpointStore keeps the checkpoint as JSON in a file.
+type FileCheckpointStore struct {
+	path string
+}
+
+//NewFileCheckpointStore returns a store keeping the checkpoint at path. The file is created on the first Save.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+//Load reads the checkpoint, returning a zero one if the file doesn't exist.
+func (s *FileCheckpointStore) Load(ctx context.Context) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, errors.Wrap(err, "can't read checkpoint")
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, errors.Wrapf(err, "can't decode checkpoint at %s", s.path)
+	}
+	return cp, nil
+}
+
+//Save writes the checkpoint to a temporary file and renames it, so a crash never leaves a partial checkpoint behind.
+func (s *FileCheckpointStore) Save(ctx context.Context, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrap(err, "can't encode checkpoint")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "can't create checkpoint")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "can't write checkpoint")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "can't write checkpoint")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "can't write checkpoint")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), s.path), "can't save checkpoint")
+}
+
+//PollerOption configures a Poller.
+type PollerOption func(*Poller)
+
+//WithInterval sets how long Run waits between polls. It's DefaultPollInterval by default, or if d isn't positive.
+func WithInterval(d time.Duration) PollerOption {
+	return func(p *Poller) {
+		p.interval = d
+	}
+}
+
+//WithPageSize sets how many events are fetched per request. It's the iterator's default, 50, by default.
+func WithPageSize(n int) PollerOption {
+	return func(p *Poller) {
+		p.pageSize = n
+	}
+}
+
+//WithStart sets where a poller without a checkpoint starts. Without it, every event QVO kept is delivered on the first poll.
+func WithStart(t time.Time) PollerOption {
+	return func(p *Poller) {
+		p.start = t
+	}
+}
+
+//WithDedupStore records delivered event ids in store, which may be shared with the webhook handler.
+//By default they're kept in memory for DefaultDedupTTL.
+func WithDedupStore(store ReplayStore) PollerOption {
+	return func(p *Poller) {
+		p.dedup = store
+	}
+}
+
+//Poller fetches events from QVO with ListEvents and dispatches them to a Handler's handlers, for services that can't receive webhooks:
+//
+//	h := webhook.NewHandler()
+//	h.Handle(qvo.TransactionPaymentFailed, startDunning)
+//	p := webhook.NewPoller(c, h, webhook.NewFileCheckpointStore("/var/lib/billing/qvo.checkpoint"))
+//	err := p.Run(ctx)
+//
+//Events are delivered in creation order, at least once. The checkpoint is saved after each delivered event, and a failed handler stops the poll,
+//so the event is retried on the next one. Events already delivered are skipped by id, but an event may still be delivered again if the poller stops
+//between calling its handlers and saving the checkpoint, so handlers should be idempotent.
+type Poller struct {
+	client   *qvo.Client
+	handler  *Handler
+	store    CheckpointStore
+	interval time.Duration
+	pageSize int
+	start    time.Time
+	dedup    ReplayStore
+}
+
+//NewPoller returns a Poller dispatching events to h's handlers and keeping its checkpoint in store.
+func NewPoller(c *qvo.Client, h *Handler, store CheckpointStore, opts ...PollerOption) *Poller {
+	p := &Poller{client: c, handler: h, store: store, interval: DefaultPollInterval}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.interval <= 0 {
+		p.interval = DefaultPollInterval
+	}
+	if p.dedup == nil {
+		p.dedup = NewMemoryReplayStore(DefaultDedupTTL)
+	}
+	return p
+}
+
+//Run polls right away and then every interval, until ctx is done. Failed polls are logged through the handler's logger and retried on the next one.
+//It returns ctx's error.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+			p.handler.log(qvo.LevelError, "qvo event poll failed", qvo.Fields{"error": err})
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+//Poll delivers the events created since the checkpoint, in creation order, and returns how many were delivered.
+//It stops at the first event a handler fails on, returning the handler's error.
+func (p *Poller) Poll(ctx context.Context) (int, error) {
+	cp, err := p.store.Load(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "webhook: can't load checkpoint")
+	}
+
+	//Times are sent to the millisecond, so events up to the checkpoint may be fetched again. They're skipped by creation time and id.
+	since := cp.CreatedAt
+	if cp.EventID == "" {
+		since = p.start
+	}
+	//Ordering by id too keeps pages stable when events share a creation time, so none is skipped.
+	q := qvo.NewQuery().OrderBy("created_at", qvo.Asc).OrderBy("id", qvo.Asc)
+	if !since.IsZero() {
+		q = q.Gte("created_at", since)
+	}
+	if p.pageSize > 0 {
+		q = q.Page(1, p.pageSize)
+	}
+
+	//ctx may be done once a handler returns, e.g., when Run is stopping, but the dedup store must still be updated.
+	storeCtx := context.WithoutCancel(ctx)
+	delivered := 0
+	it := qvo.IterEvents(ctx, p.client, q)
+	defer it.Close()
+	for it.Next() {
+		event := it.Current()
+		if cp.EventID != "" && !after(event, cp) {
+			continue
+		}
+		claimed, err := p.dedup.Claim(ctx, event.ID, DefaultClaimLease)
+		if err != nil {
+			return delivered, errors.Wrap(err, "webhook: can't check dedup store")
+		}
+
+		if claimed {
+			if err := p.handler.dispatch(ctx, event); err != nil {
+				if err := p.dedup.Release(storeCtx, event.ID); err != nil {
+					p.handler.log(qvo.LevelError, "qvo event dedup store failed", qvo.Fields{"event_id": event.ID, "error": err})
+				}
+				return delivered, errors.Wrapf(err, "webhook: handler failed for event %s", event.ID)
+			}
+			if err := p.dedup.Add(storeCtx, event.ID); err != nil {
+				p.handler.log(qvo.LevelError, "qvo event dedup store failed", qvo.Fields{"event_id": event.ID, "error": err})
+			}
+			delivered++
+		}
+
+		cp = Checkpoint{EventID: event.ID, CreatedAt: event.CreatedAt}
+		if err := p.store.Save(ctx, cp); err != nil {
+			return delivered, errors.Wrap(err, "webhook: can't save checkpoint")
+		}
+	}
+	if err := it.Err(); err != nil {
+		return delivered, errors.Wrap(err, "webhook: can't list events")
+	}
+	return delivered, nil
+}
+
+//after tells if an event comes after the checkpoint in the poller's order: by creation time, then id.
+func after(event qvo.Event, cp Checkpoint) bool {
+	if !event.CreatedAt.Equal(cp.CreatedAt) {
+		return event.CreatedAt.After(cp.CreatedAt)
+	}
+	return event.ID > cp.EventID
+}
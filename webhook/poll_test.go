@@ -0,0 +1,169 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/iegomez/qvo-go-client/webhook"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPoller(t *testing.T) {
+	Convey("Given a fake server with two customers and a poller delivering their events", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		c := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL))
+		ctx := context.Background()
+		created := 0
+		createCustomer := func() {
+			created++
+			_, err := qvo.CreateCustomer(c, fmt.Sprintf("Customer %d", created), fmt.Sprintf("test%d@manglar.cl", created))
+			So(err, ShouldBeNil)
+		}
+		createCustomer()
+		createCustomer()
+
+		var emails []string
+		var failing error
+		h := webhook.NewHandler()
+		h.Handle(qvo.CustomerCreated, func(ctx context.Context, event qvo.Event) error {
+			if failing != nil {
+				return failing
+			}
+			customer, err := qvo.DecodeData[qvo.Customer](event)
+			emails = append(emails, customer.Email)
+			return err
+		})
+		store := &webhook.MemoryCheckpointStore{}
+		p := webhook.NewPoller(c, h, store, webhook.WithPageSize(1))
+
+		Convey("Each poll should deliver the events created since the last one, in order", func() {
+			n, err := p.Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 2)
+			So(emails, ShouldResemble, []string{"test1@manglar.cl", "test2@manglar.cl"})
+
+			createCustomer()
+			n, err = p.Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 1)
+			n, err = p.Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 0)
+			So(emails, ShouldResemble, []string{"test1@manglar.cl", "test2@manglar.cl", "test3@manglar.cl"})
+
+			events, err := qvo.ListEvents(c, 0, 0, nil, "created_at DESC")
+			So(err, ShouldBeNil)
+			cp, err := store.Load(ctx)
+			So(err, ShouldBeNil)
+			So(cp.EventID, ShouldEqual, events[0].ID)
+		})
+
+		Convey("Events sharing a creation time should be ordered by id, so pages stay stable", func() {
+			_, err := p.Poll(ctx)
+			So(err, ShouldBeNil)
+			requests := srv.Requests()
+			So(requests[len(requests)-1].Form.Get("order_by"), ShouldEqual, "created_at ASC, id ASC")
+		})
+
+		Convey("An event a handler failed on should be retried on the next poll", func() {
+			_, err := p.Poll(ctx)
+			So(err, ShouldBeNil)
+			before, _ := store.Load(ctx)
+
+			createCustomer()
+			failing = errors.New("database down")
+			n, err := p.Poll(ctx)
+			So(errors.Is(err, failing), ShouldBeTrue)
+			So(n, ShouldEqual, 0)
+			after, _ := store.Load(ctx)
+			So(after, ShouldResemble, before)
+
+			failing = nil
+			n, err = p.Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 1)
+			So(emails, ShouldHaveLength, 3)
+		})
+
+		Convey("A restarted poller should resume from its file checkpoint", func() {
+			path := filepath.Join(t.TempDir(), "qvo.checkpoint")
+			_, err := webhook.NewPoller(c, h, webhook.NewFileCheckpointStore(path)).Poll(ctx)
+			So(err, ShouldBeNil)
+
+			createCustomer()
+			n, err := webhook.NewPoller(c, h, webhook.NewFileCheckpointStore(path)).Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 1)
+			So(emails, ShouldResemble, []string{"test1@manglar.cl", "test2@manglar.cl", "test3@manglar.cl"})
+		})
+
+		Convey("An event whose handler failed as the poll was canceled should be delivered by the next one", func() {
+			dedup := &ctxReplayStore{webhook.NewMemoryReplayStore(time.Hour)}
+			pollCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			stopping := webhook.NewHandler()
+			stopping.Handle(qvo.CustomerCreated, func(ctx context.Context, event qvo.Event) error {
+				cancel()
+				return ctx.Err()
+			})
+			_, err := webhook.NewPoller(c, stopping, store, webhook.WithDedupStore(dedup)).Poll(pollCtx)
+			So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+			n, err := webhook.NewPoller(c, h, store, webhook.WithDedupStore(dedup)).Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 2)
+		})
+
+		Convey("A non-positive interval should fall back to the default instead of panicking", func() {
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			err := webhook.NewPoller(c, h, store, webhook.WithInterval(0)).Run(ctx)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(emails, ShouldHaveLength, 2)
+		})
+
+		Convey("Events delivered through the shared dedup store should be skipped", func() {
+			dedup := webhook.NewMemoryReplayStore(time.Hour)
+			events, err := qvo.ListEvents(c, 0, 0, nil, "created_at ASC")
+			So(err, ShouldBeNil)
+			So(dedup.Add(ctx, events[0].ID), ShouldBeNil)
+
+			n, err := webhook.NewPoller(c, h, &webhook.MemoryCheckpointStore{}, webhook.WithDedupStore(dedup)).Poll(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 1)
+			So(emails, ShouldResemble, []string{"test2@manglar.cl"})
+		})
+
+		Convey("Run should poll until the context is done", func() {
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			err := webhook.NewPoller(c, h, store, webhook.WithInterval(5*time.Millisecond)).Run(ctx)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(emails, ShouldHaveLength, 2)
+		})
+	})
+
+	Convey("Given a file checkpoint store", t, func() {
+		store := webhook.NewFileCheckpointStore(filepath.Join(t.TempDir(), "qvo.checkpoint"))
+		ctx := context.Background()
+
+		Convey("It should be empty until saved", func() {
+			cp, err := store.Load(ctx)
+			So(err, ShouldBeNil)
+			So(cp, ShouldResemble, webhook.Checkpoint{})
+
+			saved := webhook.Checkpoint{EventID: "evt_1", CreatedAt: time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)}
+			So(store.Save(ctx, saved), ShouldBeNil)
+			cp, err = store.Load(ctx)
+			So(err, ShouldBeNil)
+			So(cp, ShouldResemble, saved)
+		})
+	})
+}
@@ -0,0 +1,275 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/pkg/errors"
+)
+
+//Reason tells why a delivery was rejected.
+type Reason string
+
+//Rejection reasons.
+const (
+	ReasonMissingSignature Reason = "missing_signature" //The request isn't signed.
+	ReasonInvalidSignature Reason = "invalid_signature" //The signature doesn't match the body.
+	ReasonStale            Reason = "stale"             //The timestamp is out of the tolerance.
+	ReasonUnknownEvent     Reason = "unknown_event"     //QVO doesn't know the event.
+	ReasonMismatch         Reason = "mismatch"          //The event differs from the one QVO has.
+	ReasonReplayed         Reason = "replayed"          //The event was already accepted.
+)
+
+//ErrVerification is matched with errors.Is by every *VerificationError.
+var ErrVerification = errors.New("webhook: delivery verification failed")
+
+//VerificationError is why a delivery was rejected. Replayed deliveries are acknowledged with a 200 without calling any handler, and the rest are answered with a 401.
+type VerificationError struct {
+	Reason  Reason
+	EventID string //Empty if the event wasn't parsed.
+	Err     error  //Underlying error, if any.
+}
+
+//Error describes the rejection.
+func (e *VerificationError) Error() string {
+	msg := fmt.Sprintf("webhook: delivery rejected (%s)", e.Reason)
+	if e.EventID != "" {
+		msg += " for event " + e.EventID
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+//Unwrap returns the underlying error.
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+//Is makes errors.Is(err, ErrVerification) work.
+func (e *VerificationError) Is(target error) bool {
+	return target == ErrVerification
+}
+
+//status returns the status a rejected delivery is answered with.
+func (e *VerificationError) status() int {
+	if e.Reason == ReasonReplayed {
+		return http.StatusOK
+	}
+	return http.StatusUnauthorized
+}
+
+//Verifier checks a delivery is authentic before it's dispatched. It returns the event to dispatch, or a *VerificationError.
+//Other errors, such as failing to reach QVO, are answered with a 500 so QVO retries the delivery.
+type Verifier interface {
+	Verify(ctx context.Context, header http.Header, body []byte, event qvo.Event) (qvo.Event, error)
+}
+
+//VerifierFunc adapts a function to Verifier.
+type VerifierFunc func(ctx context.Context, header http.Header, body []byte, event qvo.Event) (qvo.Event, error)
+
+//Verify calls f.
+func (f VerifierFunc) Verify(ctx context.Context, header http.Header, body []byte, event qvo.Event) (qvo.Event, error) {
+	return f(ctx, header, body, event)
+}
+
+//Defaults for HMACVerifier.
+const (
+	DefaultSignatureHeader = "X-QVO-Signature"
+	DefaultTimestampHeader = "X-QVO-Timestamp"
+	DefaultTolerance       = 5 * time.Minute
+)
+
+//HMACVerifier checks the HMAC-SHA256 signature of deliveries, for when QVO signs them with a shared secret.
+//The signature is hex encoded, optionally prefixed with "sha256=". When the request carries a timestamp, in unix seconds, the signed payload is "<timestamp>.<body>" and the timestamp must be within the tolerance.
+type HMACVerifier struct {
+	Secrets         []string         //Any of them may sign. More than one allows rotating them.
+	Header          string           //DefaultSignatureHeader if empty.
+	TimestampHeader string           //DefaultTimestampHeader if empty.
+	Tolerance       time.Duration    //DefaultTolerance if 0. A negative one disables the check.
+	Now             func() time.Time //time.Now if nil.
+}
+
+//Verify checks the signature and timestamp.
+func (v *HMACVerifier) Verify(ctx context.Context, header http.Header, body []byte, event qvo.Event) (qvo.Event, error) {
+	name, tsName, tolerance, now := v.Header, v.TimestampHeader, v.Tolerance, v.Now
+	if name == "" {
+		name = DefaultSignatureHeader
+	}
+	if tsName == "" {
+		tsName = DefaultTimestampHeader
+	}
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	if now == nil {
+		now = time.Now
+	}
+
+	signature := strings.TrimPrefix(header.Get(name), "sha256=")
+	if signature == "" {
+		return qvo.Event{}, &VerificationError{Reason: ReasonMissingSignature, EventID: event.ID}
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return qvo.Event{}, &VerificationError{Reason: ReasonInvalidSignature, EventID: event.ID, Err: err}
+	}
+
+	payload := body
+	if ts := header.Get(tsName); ts != "" {
+		payload = append([]byte(ts+"."), body...)
+	}
+	valid := false
+	for _, secret := range v.Secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		if hmac.Equal(got, mac.Sum(nil)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return qvo.Event{}, &VerificationError{Reason: ReasonInvalidSignature, EventID: event.ID}
+	}
+
+	//Only check the timestamp once it's known to be signed.
+	if ts := header.Get(tsName); ts != "" && tolerance > 0 {
+		secs, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return qvo.Event{}, &VerificationError{Reason: ReasonStale, EventID: event.ID, Err: err}
+		}
+		if err := checkTolerance(time.Unix(secs, 0), now(), tolerance); err != nil {
+			return qvo.Event{}, &VerificationError{Reason: ReasonStale, EventID: event.ID, Err: err}
+		}
+	}
+	return event, nil
+}
+
+//Sign returns the signature HMACVerifier expects for a body and timestamp, which may be empty. It's useful to test receivers.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	if timestamp != "" {
+		mac.Write([]byte(timestamp + "."))
+	}
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//FetchVerifier fetches each delivered event from QVO with GetEvent and dispatches the fetched one, for when deliveries aren't signed.
+//A delivery is rejected if QVO doesn't know its event, or if its type or creation time differ from QVO's.
+type FetchVerifier struct {
+	Client *qvo.Client
+}
+
+//Verify fetches the event and compares it with the delivered one.
+func (v *FetchVerifier) Verify(ctx context.Context, header http.Header, body []byte, event qvo.Event) (qvo.Event, error) {
+	if strings.ContainsAny(event.ID, "/?#") {
+		return qvo.Event{}, &VerificationError{Reason: ReasonUnknownEvent, EventID: event.ID, Err: errors.New("malformed event id")}
+	}
+
+	fetched, err := qvo.GetEventWithContext(ctx, v.Client, event.ID)
+	if errors.Is(err, qvo.ErrNotFound) {
+		return qvo.Event{}, &VerificationError{Reason: ReasonUnknownEvent, EventID: event.ID, Err: err}
+	}
+	if err != nil {
+		return qvo.Event{}, errors.Wrap(err, "webhook: can't fetch event")
+	}
+
+	if fetched.Type != event.Type || !fetched.CreatedAt.Equal(event.CreatedAt) {
+		return qvo.Event{}, &VerificationError{Reason: ReasonMismatch, EventID: event.ID}
+	}
+	return fetched, nil
+}
+
+//checkTolerance checks t is within tolerance of now.
+func checkTolerance(t, now time.Time, tolerance time.Duration) error {
+	if d := now.Sub(t); d > tolerance || d < -tolerance {
+		return errors.Errorf("timestamp %s is %s away from now, over the %s tolerance", t.Format(time.RFC3339), d.Round(time.Second), tolerance)
+	}
+	return nil
+}
+
+//ReplayStore remembers accepted event ids so repeated deliveries are rejected.
+//An id is claimed before its handlers run, so concurrent deliveries of an event don't both dispatch it. The claim is released if a handler fails,
+//so the delivery QVO retries is still handled, and the id is added once every handler succeeded.
+//Claims only last for a lease, much shorter than the time accepted ids are kept, so an event whose receiver crashed mid-handler may be delivered again.
+type ReplayStore interface {
+	//Claim reserves an event id for lease, atomically. It returns false if the id was already accepted or is claimed by a delivery in flight.
+	Claim(ctx context.Context, id string, lease time.Duration) (bool, error)
+	//Release drops a claim whose handlers failed.
+	Release(ctx context.Context, id string) error
+	//Add records an accepted event id.
+	Add(ctx context.Context, id string) error
+}
+
+//MemoryReplayStore is an in-memory ReplayStore forgetting ids after a ttl. It's safe for concurrent use.
+//Use a shared store, e.g., backed by redis with SET NX, when running several receivers.
+type MemoryReplayStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+//NewMemoryReplayStore returns a store remembering ids for ttl. It should be longer than the time QVO keeps retrying deliveries.
+func NewMemoryReplayStore(ttl time.Duration) *MemoryReplayStore {
+	return &MemoryReplayStore{ttl: ttl, now: time.Now, ids: make(map[string]time.Time)}
+}
+
+//Seen tells if an id was claimed or added and hasn't expired.
+func (s *MemoryReplayStore) Seen(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.ids[id]
+	return ok && s.now().Before(expiry), nil
+}
+
+//Claim reserves an id for lease unless it's claimed or added and hasn't expired.
+func (s *MemoryReplayStore) Claim(ctx context.Context, id string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if expiry, ok := s.ids[id]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	s.put(id, now.Add(lease))
+	return true, nil
+}
+
+//Release drops an id.
+func (s *MemoryReplayStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ids, id)
+	return nil
+}
+
+//Add records an id for the store's ttl.
+func (s *MemoryReplayStore) Add(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(id, s.now().Add(s.ttl))
+	return nil
+}
+
+//put records an id until expiry, dropping expired ones.
+func (s *MemoryReplayStore) put(id string, expiry time.Time) {
+	now := s.now()
+	for k, e := range s.ids {
+		if !now.Before(e) {
+			delete(s.ids, k)
+		}
+	}
+	s.ids[id] = expiry
+}
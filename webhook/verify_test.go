@@ -0,0 +1,257 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/iegomez/qvo-go-client/qvotest"
+	"github.com/iegomez/qvo-go-client/webhook"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//post delivers a body with the given headers.
+func post(h http.Handler, body string, header http.Header) int {
+	req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHMACVerifier(t *testing.T) {
+	Convey("Given a handler verifying signatures", t, func() {
+		now := time.Now()
+		var rejected []webhook.Reason
+		var handled int
+		h := webhook.NewHandler(
+			webhook.WithVerifier(&webhook.HMACVerifier{Secrets: []string{"old-secret", "new-secret"}, Now: func() time.Time { return now }}),
+			webhook.WithReplayStore(webhook.NewMemoryReplayStore(time.Hour)),
+			webhook.OnReject(func(r *http.Request, err *webhook.VerificationError) {
+				rejected = append(rejected, err.Reason)
+			}),
+		)
+		h.Handle("*", func(ctx context.Context, event qvo.Event) error {
+			handled++
+			return nil
+		})
+		body := `{"id": "evt_1", "type": "customer.created", "data": {"id": "cus_1"}}`
+		ts := strconv.FormatInt(now.Unix(), 10)
+		signed := func(secret, ts, body string) http.Header {
+			return http.Header{
+				webhook.DefaultSignatureHeader: {"sha256=" + webhook.Sign(secret, ts, []byte(body))},
+				webhook.DefaultTimestampHeader: {ts},
+			}
+		}
+
+		Convey("A delivery signed with any secret should be handled once", func() {
+			So(post(h, body, signed("new-secret", ts, body)), ShouldEqual, http.StatusOK)
+			So(post(h, body, signed("old-secret", ts, body)), ShouldEqual, http.StatusOK)
+			So(handled, ShouldEqual, 1)
+			So(rejected, ShouldResemble, []webhook.Reason{webhook.ReasonReplayed})
+		})
+
+		Convey("Unsigned, tampered or stale deliveries should be rejected", func() {
+			So(post(h, body, nil), ShouldEqual, http.StatusUnauthorized)
+			So(post(h, body, signed("other-secret", ts, body)), ShouldEqual, http.StatusUnauthorized)
+			tampered := strings.Replace(body, "cus_1", "cus_2", 1)
+			So(post(h, tampered, signed("new-secret", ts, body)), ShouldEqual, http.StatusUnauthorized)
+			old := strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)
+			So(post(h, body, signed("new-secret", old, body)), ShouldEqual, http.StatusUnauthorized)
+
+			So(handled, ShouldEqual, 0)
+			So(rejected, ShouldResemble, []webhook.Reason{webhook.ReasonMissingSignature, webhook.ReasonInvalidSignature, webhook.ReasonInvalidSignature, webhook.ReasonStale})
+		})
+
+		Convey("Rejections should be typed errors", func() {
+			_, err := (&webhook.HMACVerifier{Secrets: []string{"secret"}}).Verify(context.Background(), http.Header{}, []byte(body), qvo.Event{ID: "evt_1"})
+			So(errors.Is(err, webhook.ErrVerification), ShouldBeTrue)
+			var verr *webhook.VerificationError
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(verr.Reason, ShouldEqual, webhook.ReasonMissingSignature)
+			So(verr.EventID, ShouldEqual, "evt_1")
+		})
+	})
+}
+
+func TestReplayStore(t *testing.T) {
+	Convey("Given a handler with a replay store and a slow handler", t, func() {
+		var handled int32
+		var failing int32
+		release := make(chan struct{})
+		store := webhook.NewMemoryReplayStore(time.Hour)
+		h := webhook.NewHandler(webhook.WithReplayStore(store))
+		h.Handle("*", func(ctx context.Context, event qvo.Event) error {
+			if atomic.LoadInt32(&failing) == 1 {
+				return errors.New("database down")
+			}
+			<-release
+			atomic.AddInt32(&handled, 1)
+			return nil
+		})
+		body := `{"id": "evt_1", "type": "customer.created"}`
+
+		Convey("Concurrent deliveries of an event should dispatch it once", func() {
+			first := make(chan int, 1)
+			go func() {
+				first <- post(h, body, nil)
+			}()
+			for seen := false; !seen; {
+				seen, _ = store.Seen(context.Background(), "evt_1")
+			}
+			//The second delivery is acknowledged while the first one is still being handled.
+			second := post(h, body, nil)
+			close(release)
+			So(<-first, ShouldEqual, http.StatusOK)
+			So(second, ShouldEqual, http.StatusOK)
+			So(atomic.LoadInt32(&handled), ShouldEqual, 1)
+		})
+
+		Convey("A failed delivery should release its claim so the retry is handled", func() {
+			close(release)
+			atomic.StoreInt32(&failing, 1)
+			So(post(h, body, nil), ShouldEqual, http.StatusInternalServerError)
+			atomic.StoreInt32(&failing, 0)
+			So(post(h, body, nil), ShouldEqual, http.StatusOK)
+			So(post(h, body, nil), ShouldEqual, http.StatusOK)
+			So(atomic.LoadInt32(&handled), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a replay store which fails with a done context, like a networked one", t, func() {
+		store := &ctxReplayStore{webhook.NewMemoryReplayStore(time.Hour)}
+		h := webhook.NewHandler(webhook.WithReplayStore(store))
+		var calls int32
+		h.Handle("*", func(ctx context.Context, event qvo.Event) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+		body := `{"id": "evt_1", "type": "customer.created"}`
+
+		Convey("A delivery whose request timed out should still release its claim", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+
+			So(post(h, body, nil), ShouldEqual, http.StatusOK)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given a memory replay store", t, func() {
+		store := webhook.NewMemoryReplayStore(time.Hour)
+		ctx := context.Background()
+
+		Convey("A claim should expire after its lease", func() {
+			claimed, err := store.Claim(ctx, "evt_1", 10*time.Millisecond)
+			So(err, ShouldBeNil)
+			So(claimed, ShouldBeTrue)
+			claimed, _ = store.Claim(ctx, "evt_1", time.Minute)
+			So(claimed, ShouldBeFalse)
+
+			time.Sleep(20 * time.Millisecond)
+			claimed, _ = store.Claim(ctx, "evt_1", 10*time.Millisecond)
+			So(claimed, ShouldBeTrue)
+
+			Convey("But an added id should be kept for the ttl", func() {
+				So(store.Add(ctx, "evt_1"), ShouldBeNil)
+				time.Sleep(20 * time.Millisecond)
+				claimed, _ := store.Claim(ctx, "evt_1", time.Minute)
+				So(claimed, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+//ctxReplayStore fails when called with a done context, like a store reached over the network.
+type ctxReplayStore struct {
+	*webhook.MemoryReplayStore
+}
+
+func (s *ctxReplayStore) Release(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryReplayStore.Release(ctx, id)
+}
+
+func TestFetchVerifier(t *testing.T) {
+	Convey("Given a fake server with an event and a handler verifying deliveries against it", t, func() {
+		srv := qvotest.NewServer()
+		defer srv.Close()
+		policy := qvo.DefaultRetryPolicy()
+		policy.MaxAttempts = 1
+		c := qvo.NewClient(srv.Token, true, qvo.WithBaseURL(srv.URL), qvo.WithRetryPolicy(policy))
+		_, err := qvo.CreateCustomer(c, "Ignacio Gómez", "test@manglar.cl")
+		So(err, ShouldBeNil)
+		events, err := qvo.ListEvents(c, 0, 0, nil, "")
+		So(err, ShouldBeNil)
+		So(events, ShouldHaveLength, 1)
+
+		var got []qvo.Event
+		store := webhook.NewMemoryReplayStore(time.Hour)
+		h := webhook.NewHandler(webhook.WithVerifier(&webhook.FetchVerifier{Client: c}), webhook.WithReplayStore(store), webhook.WithTolerance(time.Hour))
+		h.Handle("*", func(ctx context.Context, event qvo.Event) error {
+			got = append(got, event)
+			return nil
+		})
+		delivery := func(event qvo.Event) string {
+			data, _ := json.Marshal(event)
+			return string(data)
+		}
+
+		Convey("A delivery matching QVO's event should dispatch the fetched event", func() {
+			forged := events[0]
+			forged.Data = map[string]interface{}{"email": "attacker@example.com"}
+			So(post(h, delivery(forged), nil), ShouldEqual, http.StatusOK)
+			So(got, ShouldHaveLength, 1)
+			So(got[0].Data["email"], ShouldEqual, "test@manglar.cl")
+
+			seen, err := store.Seen(context.Background(), events[0].ID)
+			So(err, ShouldBeNil)
+			So(seen, ShouldBeTrue)
+		})
+
+		Convey("Unknown or different events should be rejected", func() {
+			unknown := events[0]
+			unknown.ID = "evt_nope"
+			So(post(h, delivery(unknown), nil), ShouldEqual, http.StatusUnauthorized)
+
+			different := events[0]
+			different.Type = qvo.TransactionPaymentSucceeded
+			So(post(h, delivery(different), nil), ShouldEqual, http.StatusUnauthorized)
+			So(got, ShouldBeEmpty)
+		})
+
+		Convey("Old events should be rejected", func() {
+			old := events[0]
+			old.CreatedAt = old.CreatedAt.Add(-2 * time.Hour)
+			strict := webhook.NewHandler(webhook.WithTolerance(time.Hour))
+			So(post(strict, delivery(old), nil), ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("Failing to reach QVO should make it retry", func() {
+			srv.FailNext(1, "GET", "events/*", http.StatusServiceUnavailable)
+			So(post(h, delivery(events[0]), nil), ShouldEqual, http.StatusInternalServerError)
+			So(got, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,302 @@
+//Package webhook receives QVO's webhooks, dispatching each event to the handlers registered for its type:
+//
+//	h := webhook.NewHandler()
+//	h.Handle(qvo.TransactionPaymentFailed, func(ctx context.Context, event qvo.Event) error {
+//		return dunning.Start(ctx, event)
+//	})
+//	h.Handle("customer.*", syncCustomer)
+//	http.Handle("/qvo/webhooks", h)
+//
+//A handler returning an error makes the delivery fail with a 500, so QVO retries it. As deliveries may then be repeated, handlers should be idempotent.
+//
+//Services that can't receive webhooks may poll QVO's events with a Poller instead, which dispatches them to the same handlers.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/pkg/errors"
+)
+
+//DefaultMaxBodySize is the largest body accepted by default.
+const DefaultMaxBodySize = 1 << 20
+
+//DefaultClaimLease is how long an event id is claimed in a replay store while its handlers run, by default.
+const DefaultClaimLease = 5 * time.Minute
+
+//HandlerFunc handles an event. Returning an error answers the delivery with a 500 so QVO retries it.
+type HandlerFunc func(ctx context.Context, event qvo.Event) error
+
+//route is a handler registered for a pattern.
+type route struct {
+	pattern string
+	handler HandlerFunc
+}
+
+//Option configures a Handler.
+type Option func(*Handler)
+
+//WithMaxBodySize sets the largest body accepted. Larger ones are answered with a 413. It's DefaultMaxBodySize by default.
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = n
+	}
+}
+
+//WithLogger logs rejected deliveries and failed handlers through the given logger.
+func WithLogger(logger qvo.Logger) Option {
+	return func(h *Handler) {
+		h.logger = logger
+	}
+}
+
+//WithVerifier checks every delivery with v before dispatching it. Without one, deliveries are trusted.
+func WithVerifier(v Verifier) Option {
+	return func(h *Handler) {
+		h.verifier = v
+	}
+}
+
+//WithReplayStore rejects deliveries of events already accepted, or being handled, as recorded in store.
+func WithReplayStore(store ReplayStore) Option {
+	return func(h *Handler) {
+		h.replayStore = store
+	}
+}
+
+//WithClaimLease sets how long an event id is claimed in the replay store while its handlers run. It should be longer than they take.
+//If the receiver crashes mid-handler, QVO's retries are handled once it expires. It's DefaultClaimLease by default.
+func WithClaimLease(d time.Duration) Option {
+	return func(h *Handler) {
+		h.claimLease = d
+	}
+}
+
+//WithTolerance rejects events created longer than d ago, or d in the future. It should be longer than the time QVO keeps retrying deliveries.
+func WithTolerance(d time.Duration) Option {
+	return func(h *Handler) {
+		h.tolerance = d
+	}
+}
+
+//OnReject calls f with the *VerificationError of every rejected delivery, e.g., for metrics or alerts.
+func OnReject(f func(r *http.Request, err *VerificationError)) Option {
+	return func(h *Handler) {
+		h.onReject = f
+	}
+}
+
+//Handler is an http.Handler receiving QVO's webhooks. It's safe to register handlers while it's serving.
+type Handler struct {
+	maxBodySize int64
+	logger      qvo.Logger
+	verifier    Verifier
+	replayStore ReplayStore
+	claimLease  time.Duration
+	tolerance   time.Duration
+	onReject    func(r *http.Request, err *VerificationError)
+
+	mu       sync.RWMutex
+	routes   []route
+	fallback HandlerFunc
+}
+
+//NewHandler returns a Handler without any registered handlers.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{maxBodySize: DefaultMaxBodySize, claimLease: DefaultClaimLease}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+//Handle registers a handler for the events matching pattern, which is either an event type, such as qvo.TransactionPaymentFailed, or ends with a wildcard:
+//"customer.*" matches every type starting with "customer.", including "customer.card.created", and "*" matches every type.
+//Every handler matching an event is called, in the order they were registered, until one fails.
+func (h *Handler) Handle(pattern string, handler HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routes = append(h.routes, route{pattern: pattern, handler: handler})
+}
+
+//Fallback registers a handler for the events no other handler matches. Without one, those events are acknowledged and dropped.
+func (h *Handler) Fallback(handler HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallback = handler
+}
+
+//match tells if a pattern matches an event type.
+func match(pattern, eventType string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == eventType
+}
+
+//handlers returns the handlers for an event type.
+func (h *Handler) handlers(eventType string) []HandlerFunc {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var handlers []HandlerFunc
+	for _, r := range h.routes {
+		if match(r.pattern, eventType) {
+			handlers = append(handlers, r.handler)
+		}
+	}
+	if len(handlers) == 0 && h.fallback != nil {
+		handlers = append(handlers, h.fallback)
+	}
+	return handlers
+}
+
+//ServeHTTP parses the event, verifies it and dispatches it. It answers with:
+//
+//	200 when every handler succeeded, no handler matched, or the event was already accepted.
+//	400 when the body isn't an event.
+//	401 when the delivery failed verification.
+//	405 for methods other than POST.
+//	413 when the body is larger than the limit.
+//	500 when verification couldn't be done, or a handler failed or panicked, so QVO retries.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.reject(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.maxBodySize+1))
+	if err != nil {
+		h.reject(w, http.StatusBadRequest, errors.Wrap(err, "can't read body"))
+		return
+	}
+	if int64(len(body)) > h.maxBodySize {
+		h.reject(w, http.StatusRequestEntityTooLarge, errors.Errorf("body larger than %d bytes", h.maxBodySize))
+		return
+	}
+
+	event, err := parse(body)
+	if err != nil {
+		h.reject(w, http.StatusBadRequest, err)
+		return
+	}
+
+	event, err = h.verify(r.Context(), r.Header, body, event)
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		if h.onReject != nil {
+			h.onReject(r, verr)
+		}
+		h.reject(w, verr.status(), verr)
+		return
+	}
+	if err != nil {
+		h.log(qvo.LevelError, "qvo webhook verification failed", qvo.Fields{"event_id": event.ID, "error": err})
+		http.Error(w, "can't verify delivery", http.StatusInternalServerError)
+		return
+	}
+
+	//The request's context may be done once handlers return, e.g., when a handler timed out, but the replay store must still be updated.
+	storeCtx := context.WithoutCancel(r.Context())
+	if err := h.dispatch(r.Context(), event); err != nil {
+		h.log(qvo.LevelError, "qvo webhook handler failed", qvo.Fields{"event_id": event.ID, "event_type": event.Type, "error": err})
+		if h.replayStore != nil {
+			if err := h.replayStore.Release(storeCtx, event.ID); err != nil {
+				h.log(qvo.LevelError, "qvo webhook replay store failed", qvo.Fields{"event_id": event.ID, "error": err})
+			}
+		}
+		http.Error(w, "handler failed", http.StatusInternalServerError)
+		return
+	}
+
+	if h.replayStore != nil {
+		if err := h.replayStore.Add(storeCtx, event.ID); err != nil {
+			h.log(qvo.LevelError, "qvo webhook replay store failed", qvo.Fields{"event_id": event.ID, "error": err})
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+//verify runs the verifier, then checks the event's age and claims it in the replay store.
+func (h *Handler) verify(ctx context.Context, header http.Header, body []byte, event qvo.Event) (qvo.Event, error) {
+	if h.verifier != nil {
+		verified, err := h.verifier.Verify(ctx, header, body, event)
+		if err != nil {
+			return event, err
+		}
+		event = verified
+	}
+
+	if h.tolerance > 0 {
+		if err := checkTolerance(event.CreatedAt, time.Now(), h.tolerance); err != nil {
+			return event, &VerificationError{Reason: ReasonStale, EventID: event.ID, Err: err}
+		}
+	}
+
+	if h.replayStore != nil {
+		claimed, err := h.replayStore.Claim(ctx, event.ID, h.claimLease)
+		if err != nil {
+			return event, errors.Wrap(err, "webhook: can't check replay store")
+		}
+		if !claimed {
+			return event, &VerificationError{Reason: ReasonReplayed, EventID: event.ID}
+		}
+	}
+	return event, nil
+}
+
+//parse decodes an event, checking it has an id and a type.
+func parse(body []byte) (qvo.Event, error) {
+	var event qvo.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return qvo.Event{}, errors.Wrap(err, "can't decode event")
+	}
+	if event.ID == "" || event.Type == "" {
+		return qvo.Event{}, errors.New("event without id or type")
+	}
+	return event, nil
+}
+
+//dispatch calls the event's handlers, turning panics into errors.
+func (h *Handler) dispatch(ctx context.Context, event qvo.Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+
+	for _, handler := range h.handlers(event.Type) {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//reject answers a delivery that couldn't be handled.
+func (h *Handler) reject(w http.ResponseWriter, status int, err error) {
+	h.log(qvo.LevelWarn, "qvo webhook rejected", qvo.Fields{"status": status, "error": err})
+	if status == http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+//log logs through the handler's logger, if any.
+func (h *Handler) log(level qvo.LogLevel, msg string, fields qvo.Fields) {
+	if h.logger != nil && h.logger.Enabled(level) {
+		h.logger.Log(level, msg, fields)
+	}
+}
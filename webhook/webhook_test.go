@@ -0,0 +1,70 @@
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qvo "github.com/iegomez/qvo-go-client"
+	"github.com/iegomez/qvo-go-client/webhook"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//deliver posts a body to a handler and returns the status.
+func deliver(h http.Handler, method, body string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, "/webhooks", strings.NewReader(body)))
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	Convey("Given a webhook handler with handlers for some events", t, func() {
+		h := webhook.NewHandler(webhook.WithMaxBodySize(512))
+		var calls []string
+		record := func(name string) webhook.HandlerFunc {
+			return func(ctx context.Context, event qvo.Event) error {
+				calls = append(calls, name+":"+event.ID)
+				return nil
+			}
+		}
+		h.Handle(qvo.TransactionPaymentFailed, record("failed"))
+		h.Handle("transaction.*", record("transaction"))
+		h.Handle(qvo.CustomerCreated, func(ctx context.Context, event qvo.Event) error {
+			return errors.New("database down")
+		})
+		h.Handle(qvo.PlanDeleted, func(ctx context.Context, event qvo.Event) error {
+			panic("boom")
+		})
+
+		Convey("An event should go to every handler matching its type", func() {
+			status := deliver(h, "POST", `{"id": "evt_1", "type": "transaction.payment_failed", "data": {"id": "trx_1"}}`)
+			So(status, ShouldEqual, http.StatusOK)
+			So(calls, ShouldResemble, []string{"failed:evt_1", "transaction:evt_1"})
+		})
+
+		Convey("Unmatched events should go to the fallback, or be acknowledged without one", func() {
+			So(deliver(h, "POST", `{"id": "evt_2", "type": "withdrawal.created"}`), ShouldEqual, http.StatusOK)
+			So(calls, ShouldBeEmpty)
+
+			h.Fallback(record("fallback"))
+			So(deliver(h, "POST", `{"id": "evt_3", "type": "withdrawal.created"}`), ShouldEqual, http.StatusOK)
+			So(calls, ShouldResemble, []string{"fallback:evt_3"})
+		})
+
+		Convey("Failed or panicking handlers should answer with a 500 so QVO retries", func() {
+			So(deliver(h, "POST", `{"id": "evt_4", "type": "customer.created"}`), ShouldEqual, http.StatusInternalServerError)
+			So(deliver(h, "POST", `{"id": "evt_5", "type": "plan.deleted"}`), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("Bad deliveries should be rejected", func() {
+			So(deliver(h, "GET", ""), ShouldEqual, http.StatusMethodNotAllowed)
+			So(deliver(h, "POST", `{"id": "evt_6"`), ShouldEqual, http.StatusBadRequest)
+			So(deliver(h, "POST", `{"type": "customer.created"}`), ShouldEqual, http.StatusBadRequest)
+			So(deliver(h, "POST", `{"id": "evt_7", "type": "customer.created", "data": {"name": "`+strings.Repeat("a", 1024)+`"}}`), ShouldEqual, http.StatusRequestEntityTooLarge)
+			So(calls, ShouldBeEmpty)
+		})
+	})
+}
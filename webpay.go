@@ -1,7 +1,7 @@
 package qvo
 
 import (
-	"encoding/json"
+	"context"
 	"net/url"
 	"strconv"
 	"time"
@@ -14,8 +14,36 @@ type WebpayResponse struct {
 	ExpirationDate time.Time `json:"expiration_date"`
 }
 
-//WebpayTransaction begins a webpay transaction. If everything's ok, it'll return a transaction id (for later check), the redirect url to send the customer to, and the expiration date for this transaction.
+//WebpayService handles Webpay Plus transactions. A Client exposes it as its Webpay field, which may be replaced with a fake in tests.
+type WebpayService interface {
+	Transaction(ctx context.Context, customerID, returnURL, description string, amount int64) (WebpayResponse, error)
+}
+
+//webpayService implements WebpayService through a Client.
+type webpayService struct {
+	c *Client
+}
+
+//webpay returns the client's WebpayService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) webpay() WebpayService {
+	if c.Webpay != nil {
+		return c.Webpay
+	}
+	return &webpayService{c: c}
+}
+
+//WebpayTransaction calls WebpayTransactionWithContext with a background context.
 func WebpayTransaction(c *Client, customerID, returnURL, description string, amount int64) (WebpayResponse, error) {
+	return WebpayTransactionWithContext(context.Background(), c, customerID, returnURL, description, amount)
+}
+
+//WebpayTransactionWithContext calls Transaction on the client's WebpayService.
+func WebpayTransactionWithContext(ctx context.Context, c *Client, customerID, returnURL, description string, amount int64) (WebpayResponse, error) {
+	return c.webpay().Transaction(ctx, customerID, returnURL, description, amount)
+}
+
+//Transaction begins a webpay transaction. If everything's ok, it'll return a transaction id (for later check), the redirect url to send the customer to, and the expiration date for this transaction.
+func (s *webpayService) Transaction(ctx context.Context, customerID, returnURL, description string, amount int64) (WebpayResponse, error) {
 
 	form := url.Values{}
 	form.Add("amount", strconv.FormatInt(amount, 10))
@@ -23,18 +51,5 @@ func WebpayTransaction(c *Client, customerID, returnURL, description string, amo
 	form.Add("return_url", returnURL)
 	form.Add("Description", description)
 
-	body, err := c.request("POST", "webpay_plus/charge", form)
-	if err != nil {
-		return WebpayResponse{}, err
-	}
-
-	var resp WebpayResponse
-	err = json.Unmarshal(body, &resp)
-
-	if err != nil {
-		return WebpayResponse{}, err
-	}
-
-	return resp, nil
-
+	return call[WebpayResponse](ctx, s.c, "POST", "webpay_plus/charge", form)
 }
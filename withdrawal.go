@@ -1,15 +1,12 @@
 package qvo
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 //Withdrawal struct to represent a qvo withdraw object.
@@ -21,10 +18,42 @@ type Withdrawal struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-//CreateWithdrawal creates a withdrawal of the given amount. Return a Withdrawal object or an error.
+//WithdrawalService handles withdrawals. A Client exposes it as its Withdrawals field, which may be replaced with a fake in tests.
+type WithdrawalService interface {
+	Create(ctx context.Context, amount int64) (Withdrawal, error)
+	Get(ctx context.Context, id string) (Withdrawal, error)
+	List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Withdrawal, error)
+	ListByQuery(ctx context.Context, q *Query) ([]Withdrawal, error)
+}
+
+//withdrawalResource is the withdrawals endpoint.
+var withdrawalResource = resource[Withdrawal]{path: "withdrawals", idKey: "withdrawal_id"}
+
+//withdrawalService implements WithdrawalService through a Client.
+type withdrawalService struct {
+	c *Client
+}
+
+//withdrawals returns the client's WithdrawalService, or one backed by the client itself for clients not created with NewClient.
+func (c *Client) withdrawals() WithdrawalService {
+	if c.Withdrawals != nil {
+		return c.Withdrawals
+	}
+	return &withdrawalService{c: c}
+}
+
+//CreateWithdrawal calls CreateWithdrawalWithContext with a background context.
 func CreateWithdrawal(c *Client, amount int64) (Withdrawal, error) {
+	return CreateWithdrawalWithContext(context.Background(), c, amount)
+}
+
+//CreateWithdrawalWithContext calls Create on the client's WithdrawalService.
+func CreateWithdrawalWithContext(ctx context.Context, c *Client, amount int64) (Withdrawal, error) {
+	return c.withdrawals().Create(ctx, amount)
+}
 
-	var withdrawal Withdrawal
+//Create creates a withdrawal of the given amount. Return a Withdrawal object or an error.
+func (s *withdrawalService) Create(ctx context.Context, amount int64) (Withdrawal, error) {
 
 	//Validate required fields.
 	if amount <= 0 {
@@ -34,82 +63,50 @@ func CreateWithdrawal(c *Client, amount int64) (Withdrawal, error) {
 	form := url.Values{}
 	form.Add("amount", strconv.FormatInt(amount, 10))
 
-	body, err := c.request("POST", "withdrawals", form)
-	if err != nil {
-		return Withdrawal{}, err
-	}
-
-	err = json.Unmarshal(body, &withdrawal)
-
-	if err != nil {
-		return Withdrawal{}, err
-	}
-
-	return withdrawal, nil
+	return withdrawalResource.create(ctx, s.c, form)
 }
 
-//GetWithdrawal retrieves a withdrawal given its id.
+//GetWithdrawal calls GetWithdrawalWithContext with a background context.
 func GetWithdrawal(c *Client, id string) (Withdrawal, error) {
+	return GetWithdrawalWithContext(context.Background(), c, id)
+}
 
-	endpoint := fmt.Sprintf("withdrawals/%s", id)
-
-	form := url.Values{}
-	form.Add("withdrawal_id", id)
-
-	body, err := c.request("GET", endpoint, form)
-	if err != nil {
-		return Withdrawal{}, err
-	}
-
-	var withdrawal Withdrawal
-	err = json.Unmarshal(body, &withdrawal)
-
-	if err != nil {
-		return Withdrawal{}, err
-	}
-
-	return withdrawal, nil
+//GetWithdrawalWithContext calls Get on the client's WithdrawalService.
+func GetWithdrawalWithContext(ctx context.Context, c *Client, id string) (Withdrawal, error) {
+	return c.withdrawals().Get(ctx, id)
+}
 
+//Get retrieves a withdrawal given its id.
+func (s *withdrawalService) Get(ctx context.Context, id string) (Withdrawal, error) {
+	return withdrawalResource.get(ctx, s.c, id)
 }
 
-//ListWithdrawals retrieves a list of withdrawals with given pages, filters and order.
+//ListWithdrawals calls ListWithdrawalsWithContext with a background context.
 func ListWithdrawals(c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Withdrawal, error) {
+	return ListWithdrawalsWithContext(context.Background(), c, page, perPage, where, orderBy)
+}
 
-	var withdrawals = make([]Withdrawal, 0)
-
-	form := url.Values{}
-	if page > 0 && perPage > 0 {
-		form.Add("page", strconv.Itoa(page))
-		form.Add("per_page", strconv.Itoa(perPage))
-	}
-
-	if len(where) > 0 {
-		jBytes, err := json.Marshal(where)
-		if err != nil {
-			log.Errorf("errored at where: %s", err)
-			return withdrawals, err
-		}
-		form.Add("where", string(jBytes))
-	}
-
-	if orderBy != "" {
-		form.Add("order_by", orderBy)
-	}
-
-	body, err := c.request("GET", "withdrawals", form)
-	//log.Debugf("\n\nbody: %s\n\n", body)
-	if err != nil {
-		log.Errorf("errored at body: %s", err)
-		return withdrawals, err
-	}
+//ListWithdrawalsWithContext calls List on the client's WithdrawalService.
+func ListWithdrawalsWithContext(ctx context.Context, c *Client, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Withdrawal, error) {
+	return c.withdrawals().List(ctx, page, perPage, where, orderBy)
+}
 
-	err = json.NewDecoder(bytes.NewReader(body)).Decode(&withdrawals)
+//List retrieves a list of withdrawals with given pages, filters and order.
+func (s *withdrawalService) List(ctx context.Context, page, perPage int, where map[string]map[string]interface{}, orderBy string) ([]Withdrawal, error) {
+	return withdrawalResource.list(ctx, s.c, page, perPage, where, orderBy)
+}
 
-	if err != nil {
-		log.Errorf("errored at unmarshal: %s", err)
-		return withdrawals, err
-	}
+//ListWithdrawalsByQuery calls ListWithdrawalsByQueryWithContext with a background context.
+func ListWithdrawalsByQuery(c *Client, q *Query) ([]Withdrawal, error) {
+	return ListWithdrawalsByQueryWithContext(context.Background(), c, q)
+}
 
-	return withdrawals, nil
+//ListWithdrawalsByQueryWithContext calls ListByQuery on the client's WithdrawalService.
+func ListWithdrawalsByQueryWithContext(ctx context.Context, c *Client, q *Query) ([]Withdrawal, error) {
+	return c.withdrawals().ListByQuery(ctx, q)
+}
 
+//ListByQuery retrieves a list of withdrawals matching a query, after checking its fields are withdrawal fields.
+func (s *withdrawalService) ListByQuery(ctx context.Context, q *Query) ([]Withdrawal, error) {
+	return withdrawalResource.listByQuery(ctx, s.c, q)
 }